@@ -0,0 +1,216 @@
+package binding
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 请求内容类型
+const (
+	MIMEJSON              = "application/json"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEYAML              = "application/x-yaml"
+	MIMEYAML2             = "application/yaml"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+)
+
+// DefaultMaxMemory 解析 multipart 表单时保存在内存中的最大字节数，超出部分写入临时文件
+const DefaultMaxMemory = 32 << 20
+
+// ErrBind 请求数据解析失败（格式错误、类型转换失败等），可用 errors.Is(err, binding.ErrBind) 判断；
+// 校验失败返回的是 ValidationErrors
+var ErrBind = errors.New("binding: 请求数据解析失败")
+
+// bindError 包装解析阶段的错误，错误信息保持不变
+type bindError struct {
+	err error
+}
+
+func (e bindError) Error() string        { return e.err.Error() }
+func (e bindError) Unwrap() error        { return e.err }
+func (e bindError) Is(target error) bool { return target == ErrBind }
+
+// bind 解析请求数据后校验
+func bind(obj interface{}, decode func() error) error {
+	if err := decode(); err != nil {
+		return bindError{err}
+	}
+	return Validate(obj)
+}
+
+// Binding 请求数据绑定器，解析请求后校验 binding 标签
+type Binding interface {
+	Name() string
+	Bind(req *http.Request, obj interface{}) error
+}
+
+// 内置绑定器
+var (
+	JSON          Binding = jsonBinding{}
+	XML           Binding = xmlBinding{}
+	YAML          Binding = yamlBinding{}
+	Form          Binding = formBinding{}
+	FormPost      Binding = formPostBinding{}
+	FormMultipart Binding = formMultipartBinding{}
+	Query         Binding = queryBinding{}
+	Header        Binding = headerBinding{}
+)
+
+// Default 根据请求方法和 Content-Type 选择绑定器
+// GET 请求绑定查询参数，其余请求按 Content-Type 选择，未知类型按表单处理
+func Default(method, contentType string) Binding {
+	if method == http.MethodGet {
+		return Form
+	}
+	switch contentType {
+	case MIMEJSON:
+		return JSON
+	case MIMEXML, MIMEXML2:
+		return XML
+	case MIMEYAML, MIMEYAML2:
+		return YAML
+	case MIMEMultipartPOSTForm:
+		return FormMultipart
+	default:
+		return Form
+	}
+}
+
+// Uri 绑定路由参数，字段使用 uri 标签，如 `uri:"table" binding:"required"`
+func Uri(params map[string]string, obj interface{}) error {
+	return bind(obj, func() error {
+		values := make(map[string][]string, len(params))
+		for key, value := range params {
+			values[key] = []string{value}
+		}
+		return mapValues(obj, values, "uri")
+	})
+}
+
+type jsonBinding struct{}
+
+func (jsonBinding) Name() string { return "json" }
+
+func (jsonBinding) Bind(req *http.Request, obj interface{}) error {
+	return bind(obj, func() error {
+		if req.Body == nil {
+			return errors.New("请求体为空")
+		}
+		return json.NewDecoder(req.Body).Decode(obj)
+	})
+}
+
+type xmlBinding struct{}
+
+func (xmlBinding) Name() string { return "xml" }
+
+func (xmlBinding) Bind(req *http.Request, obj interface{}) error {
+	return bind(obj, func() error {
+		if req.Body == nil {
+			return errors.New("请求体为空")
+		}
+		return xml.NewDecoder(req.Body).Decode(obj)
+	})
+}
+
+type yamlBinding struct{}
+
+func (yamlBinding) Name() string { return "yaml" }
+
+func (yamlBinding) Bind(req *http.Request, obj interface{}) error {
+	return bind(obj, func() error {
+		if req.Body == nil {
+			return errors.New("请求体为空")
+		}
+		if err := yaml.NewDecoder(req.Body).Decode(obj); err != nil && err != io.EOF {
+			return err
+		}
+		return nil
+	})
+}
+
+// formBinding 绑定查询参数和表单（含 multipart），字段使用 form 标签
+type formBinding struct{}
+
+func (formBinding) Name() string { return "form" }
+
+func (formBinding) Bind(req *http.Request, obj interface{}) error {
+	return bind(obj, func() error {
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+		if err := req.ParseMultipartForm(DefaultMaxMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return err
+		}
+		return mapValues(obj, req.Form, "form")
+	})
+}
+
+// formPostBinding 只绑定请求体中的表单
+type formPostBinding struct{}
+
+func (formPostBinding) Name() string { return "form-urlencoded" }
+
+func (formPostBinding) Bind(req *http.Request, obj interface{}) error {
+	return bind(obj, func() error {
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+		return mapValues(obj, req.PostForm, "form")
+	})
+}
+
+// formMultipartBinding 绑定 multipart 表单
+type formMultipartBinding struct{}
+
+func (formMultipartBinding) Name() string { return "multipart/form-data" }
+
+func (formMultipartBinding) Bind(req *http.Request, obj interface{}) error {
+	return bind(obj, func() error {
+		if err := req.ParseMultipartForm(DefaultMaxMemory); err != nil {
+			return err
+		}
+		return mapValues(obj, req.MultipartForm.Value, "form")
+	})
+}
+
+// queryBinding 只绑定查询参数
+type queryBinding struct{}
+
+func (queryBinding) Name() string { return "query" }
+
+func (queryBinding) Bind(req *http.Request, obj interface{}) error {
+	return bind(obj, func() error {
+		return mapValues(obj, req.URL.Query(), "form")
+	})
+}
+
+// headerBinding 绑定请求头，字段使用 header 标签，名称不区分大小写
+type headerBinding struct{}
+
+func (headerBinding) Name() string { return "header" }
+
+func (headerBinding) Bind(req *http.Request, obj interface{}) error {
+	return bind(obj, func() error {
+		values := make(map[string][]string, len(req.Header))
+		for key, value := range req.Header {
+			values[key] = value
+		}
+		return mapValuesWith(obj, values, "header", textproto.CanonicalMIMEHeaderKey)
+	})
+}
+
+// ContentType 提取 Content-Type 中的 MIME 类型，去掉 charset 等参数
+func ContentType(header string) string {
+	mime, _, _ := strings.Cut(header, ";")
+	return strings.ToLower(strings.TrimSpace(mime))
+}
@@ -0,0 +1,156 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type pageQuery struct {
+	PageNo   int       `form:"page_no,default=1" binding:"min=1"`
+	PageSize int       `form:"page_size,default=20" binding:"min=1,max=100"`
+	Order    string    `form:"order" binding:"omitempty,oneof=asc desc"`
+	IDs      []int64   `form:"id"`
+	Since    time.Time `form:"since" time_format:"2006-01-02"`
+	Keyword  *string   `form:"keyword"`
+}
+
+func TestQueryBinding(t *testing.T) {
+	req := httptest.NewRequest("GET", "/list?page_size=50&id=1&id=2&since=2024-05-01&keyword=go", nil)
+	var q pageQuery
+	if err := Query.Bind(req, &q); err != nil {
+		t.Fatalf("绑定失败: %v", err)
+	}
+	if q.PageNo != 1 || q.PageSize != 50 {
+		t.Errorf("page_no=%d page_size=%d，期望 1 和 50", q.PageNo, q.PageSize)
+	}
+	if !reflect.DeepEqual(q.IDs, []int64{1, 2}) {
+		t.Errorf("id=%v，期望 [1 2]", q.IDs)
+	}
+	if q.Since.Format("2006-01-02") != "2024-05-01" {
+		t.Errorf("since=%v", q.Since)
+	}
+	if q.Keyword == nil || *q.Keyword != "go" {
+		t.Errorf("keyword=%v，期望 go", q.Keyword)
+	}
+}
+
+func TestQueryBindingEmptyDefault(t *testing.T) {
+	// 管理后台常把未填写的分页参数以空值提交
+	req := httptest.NewRequest("GET", "/list?page_no=&page_size=&order=", nil)
+	var q pageQuery
+	if err := Query.Bind(req, &q); err != nil {
+		t.Fatalf("空值应使用默认值，实际 %v", err)
+	}
+	if q.PageNo != 1 || q.PageSize != 20 {
+		t.Errorf("page_no=%d page_size=%d，期望 1 和 20", q.PageNo, q.PageSize)
+	}
+	if q.Order != "" {
+		t.Errorf("order=%q，期望空", q.Order)
+	}
+}
+
+func TestQueryBindingInvalid(t *testing.T) {
+	req := httptest.NewRequest("GET", "/list?page_no=abc", nil)
+	var q pageQuery
+	err := Query.Bind(req, &q)
+	if err == nil || !strings.Contains(err.Error(), "page_no") {
+		t.Fatalf("期望 page_no 转换错误，实际 %v", err)
+	}
+	var verrs ValidationErrors
+	if errors.As(err, &verrs) {
+		t.Fatalf("类型转换错误不应是 ValidationErrors")
+	}
+	if !errors.Is(err, ErrBind) {
+		t.Fatalf("类型转换错误应能通过 errors.Is 识别为 ErrBind")
+	}
+}
+
+type signupReq struct {
+	Username string   `json:"username" binding:"required,min=3,max=16"`
+	Email    string   `json:"email" binding:"required,email"`
+	Role     string   `json:"role" binding:"oneof=admin user"`
+	Age      int      `json:"age" binding:"omitempty,min=18"`
+	Tags     []string `json:"tags" binding:"max=2"`
+	Address  struct {
+		City string `json:"city" binding:"required"`
+	} `json:"address"`
+}
+
+func TestValidate(t *testing.T) {
+	body := `{"username":"ab","email":"not-an-email","role":"guest","age":0,"tags":["a","b","c"],"address":{}}`
+	req := httptest.NewRequest("POST", "/signup", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	var r signupReq
+	err := Default(req.Method, ContentType(req.Header.Get("Content-Type"))).Bind(req, &r)
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("期望 ValidationErrors，实际 %v", err)
+	}
+
+	got := map[string]string{}
+	for _, fe := range verrs {
+		got[fe.Field] = fe.Rule
+	}
+	want := map[string]string{
+		"username":     "min",
+		"email":        "email",
+		"role":         "oneof",
+		"tags":         "max",
+		"address.city": "required",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("校验结果 %v，期望 %v", got, want)
+	}
+}
+
+func TestValidatePass(t *testing.T) {
+	r := signupReq{Username: "张三丰", Email: "a@example.com", Role: "admin", Age: 20}
+	r.Address.City = "杭州"
+	if err := Validate(&r); err != nil {
+		t.Fatalf("期望通过，实际 %v", err)
+	}
+}
+
+func TestHeaderAndUri(t *testing.T) {
+	var h struct {
+		Token   string `header:"x-token" binding:"required"`
+		Version int    `header:"X-Version,default=1"`
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Token", "abc")
+	if err := Header.Bind(req, &h); err != nil || h.Token != "abc" || h.Version != 1 {
+		t.Fatalf("header 绑定结果 %+v，错误 %v", h, err)
+	}
+
+	var u struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := Uri(map[string]string{"id": "42"}, &u); err != nil || u.ID != 42 {
+		t.Fatalf("uri 绑定结果 %+v，错误 %v", u, err)
+	}
+	if err := Uri(map[string]string{}, &u); err != nil {
+		t.Fatalf("已有值的字段不应被清空: %v", err)
+	}
+}
+
+func TestFormBinding(t *testing.T) {
+	req := httptest.NewRequest("POST", "/form?from=query", strings.NewReader("name=gyweb&agree=on"))
+	req.Header.Set("Content-Type", MIMEPOSTForm)
+	var f struct {
+		Name  string `form:"name" binding:"required"`
+		Agree bool   `form:"agree"`
+		From  string `form:"from"`
+	}
+	if err := Default(http.MethodPost, MIMEPOSTForm).Bind(req, &f); err != nil {
+		t.Fatalf("绑定失败: %v", err)
+	}
+	if f.Name != "gyweb" || !f.Agree || f.From != "query" {
+		t.Fatalf("表单绑定结果 %+v", f)
+	}
+}
@@ -0,0 +1,265 @@
+package binding
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// mapValues 按标签把 values 中的值写入 obj 指向的结构体
+func mapValues(obj interface{}, values map[string][]string, tag string) error {
+	return mapValuesWith(obj, values, tag, nil)
+}
+
+// mapValuesWith 按标签把 values 中的值写入 obj 指向的结构体，key 不为 nil 时先转换标签名再查找
+//
+// 标签格式为 `form:"name,default=value"`，未写标签时使用字段名，"-" 表示忽略，参数缺失或为空时使用 default；
+// 支持基本类型、指针、切片（同名多值）、time.Time（time_format 标签指定格式，默认 RFC3339）、
+// time.Duration、实现 encoding.TextUnmarshaler 的类型以及嵌套结构体
+func mapValuesWith(obj interface{}, values map[string][]string, tag string, key func(string) string) error {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("binding: 绑定目标必须是非 nil 的结构体指针")
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return errors.New("binding: 绑定目标必须是非 nil 的结构体指针")
+	}
+	return mapStruct(rv, values, tag, key)
+}
+
+func mapStruct(rv reflect.Value, values map[string][]string, tag string, key func(string) string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+
+		tagValue, tagged := field.Tag.Lookup(tag)
+		if tagValue == "-" {
+			continue
+		}
+		name, defaultValue, hasDefault := parseTag(tagValue)
+
+		// 未写标签的嵌套结构体按其字段继续绑定
+		if !tagged && isNestedStruct(field.Type) {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv.Set(reflect.New(field.Type.Elem()))
+				}
+				fv = fv.Elem()
+			}
+			if err := mapStruct(fv, values, tag, key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if key != nil {
+			name = key(name)
+		}
+		vs, ok := values[name]
+		if !ok || len(vs) == 0 || (hasDefault && vs[0] == "") {
+			// 缺少参数或参数为空（如 ?page_no=）时使用默认值
+			if !hasDefault {
+				continue
+			}
+			vs = []string{defaultValue}
+		}
+		if err := setField(fv, field, vs); err != nil {
+			return fmt.Errorf("binding: 字段 %s 的值 %q 无效: %w", name, strings.Join(vs, ","), err)
+		}
+	}
+	return nil
+}
+
+// parseTag 解析 "name,default=value"
+func parseTag(tag string) (name, defaultValue string, hasDefault bool) {
+	name, options, _ := strings.Cut(tag, ",")
+	for options != "" {
+		var option string
+		option, options, _ = strings.Cut(options, ",")
+		if v, ok := strings.CutPrefix(option, "default="); ok {
+			defaultValue, hasDefault = v, true
+		}
+	}
+	return name, defaultValue, hasDefault
+}
+
+// isNestedStruct 判断字段是否为需要递归绑定的结构体
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// setField 把字符串值写入字段，切片和数组按多值处理，其余类型取第一个值
+func setField(fv reflect.Value, field reflect.StructField, vs []string) error {
+	switch fv.Kind() {
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			// []byte 按字符串处理
+			fv.SetBytes([]byte(vs[0]))
+			return nil
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(vs), len(vs))
+		for i, s := range vs {
+			if err := setValue(slice.Index(i), field, s); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(vs) != fv.Len() {
+			return fmt.Errorf("需要 %d 个值，实际 %d 个", fv.Len(), len(vs))
+		}
+		for i, s := range vs {
+			if err := setValue(fv.Index(i), field, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return setValue(fv, field, vs[0])
+	}
+}
+
+// setValue 把单个字符串转换为字段类型
+func setValue(fv reflect.Value, field reflect.StructField, s string) error {
+	if fv.Kind() == reflect.Ptr {
+		elem := reflect.New(fv.Type().Elem())
+		if err := setValue(elem.Elem(), field, s); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	}
+
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) && fv.Type() != timeType {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch fv.Type() {
+	case timeType:
+		return setTime(fv, field, s)
+	case durationType:
+		if s == "" {
+			fv.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			fv.SetBool(false)
+			return nil
+		}
+		switch strings.ToLower(s) {
+		case "on", "yes":
+			fv.SetBool(true)
+			return nil
+		case "off", "no":
+			fv.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			fv.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			fv.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			fv.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Interface:
+		fv.Set(reflect.ValueOf(s))
+	default:
+		return fmt.Errorf("不支持的字段类型 %s", fv.Type())
+	}
+	return nil
+}
+
+// setTime 解析时间，time_format 指定格式，time_utc:"1" 使用 UTC，否则使用本地时区
+func setTime(fv reflect.Value, field reflect.StructField, s string) error {
+	if s == "" {
+		fv.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	layout := field.Tag.Get("time_format")
+	switch layout {
+	case "":
+		layout = time.RFC3339
+	case "unix":
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(time.Unix(sec, 0)))
+		return nil
+	}
+	loc := time.Local
+	if field.Tag.Get("time_utc") == "1" {
+		loc = time.UTC
+	}
+	t, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return err
+	}
+	fv.Set(reflect.ValueOf(t))
+	return nil
+}
@@ -0,0 +1,262 @@
+package binding
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	// Field 字段名，优先使用 json 标签，其次 form、uri、header 标签，最后为结构体字段名
+	Field string `json:"field"`
+	// Rule 未通过的规则，如 required、min
+	Rule string `json:"rule"`
+	// Param 规则参数，如 min=1 中的 1
+	Param string `json:"param,omitempty"`
+	// Message 错误描述
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + e.Message
+}
+
+// ValidationErrors 校验失败的字段列表
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// RuleFunc 自定义校验规则，value 为字段值（指针已解引用），param 为等号后的参数
+type RuleFunc func(value reflect.Value, param string) bool
+
+// customRule 自定义规则及其错误描述
+type customRule struct {
+	fn      RuleFunc
+	message string
+}
+
+var (
+	rulesMu     sync.RWMutex
+	customRules = map[string]customRule{}
+)
+
+// RegisterRule 注册自定义校验规则，message 为校验失败时的描述，可包含 %s 代表参数
+//
+//	binding.RegisterRule("mobile", func(v reflect.Value, _ string) bool {
+//		return len(v.String()) == 11
+//	}, "必须是有效的手机号")
+func RegisterRule(name string, fn RuleFunc, message string) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	customRules[name] = customRule{fn: fn, message: message}
+}
+
+// Validate 按 binding 标签校验结构体，obj 可以是结构体、结构体指针或其切片，其他类型直接通过
+//
+// 内置规则：
+//   - required 不能为零值（字符串不能为空，切片和 map 不能为空，指针不能为 nil）
+//   - omitempty 零值时跳过其余规则
+//   - min=n / max=n 数值比较大小，字符串比较字符数，切片和 map 比较元素个数
+//   - len=n 字符串字符数或切片元素个数必须等于 n
+//   - email 必须是有效的邮箱地址
+//   - oneof=a b c 必须是列出的值之一
+//
+// 嵌套结构体及结构体切片会递归校验，字段名以 . 连接，如 items[0].name
+func Validate(obj interface{}) error {
+	var errs ValidationErrors
+	validateValue(reflect.ValueOf(obj), "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateValue(v reflect.Value, prefix string, errs *ValidationErrors) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() != timeType {
+			validateStruct(v, prefix, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", prefix, i), errs)
+		}
+	}
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		name := fieldName(field)
+		if prefix != "" && !field.Anonymous {
+			name = prefix + "." + name
+		} else if field.Anonymous {
+			name = prefix
+		}
+
+		if rules := field.Tag.Get("binding"); rules != "" && rules != "-" {
+			if fe, ok := checkRules(fv, rules); !ok {
+				fe.Field = name
+				*errs = append(*errs, fe)
+				continue
+			}
+		}
+		validateValue(fv, name, errs)
+	}
+}
+
+// fieldName 返回错误信息中使用的字段名
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri", "header"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// checkRules 依次检查字段的规则，返回第一个未通过的规则
+func checkRules(fv reflect.Value, rules string) (FieldError, bool) {
+	list := strings.Split(rules, ",")
+	for _, rule := range list {
+		if rule == "omitempty" && fv.IsZero() {
+			return FieldError{}, true
+		}
+	}
+
+	// 指针解引用后再比较，nil 指针只检查 required
+	value := fv
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			break
+		}
+		value = value.Elem()
+	}
+
+	for _, rule := range list {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if name == "" || name == "omitempty" {
+			continue
+		}
+		if name == "required" {
+			if fv.IsZero() || (hasLen(value) && value.Len() == 0) {
+				return FieldError{Rule: name, Message: "不能为空"}, false
+			}
+			continue
+		}
+		if (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && value.IsNil() {
+			continue
+		}
+		if ok, message := checkRule(value, name, param); !ok {
+			return FieldError{Rule: name, Param: param, Message: message}, false
+		}
+	}
+	return FieldError{}, true
+}
+
+func hasLen(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// checkRule 检查单条规则，返回是否通过及失败描述
+func checkRule(v reflect.Value, name, param string) (bool, string) {
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("binding: 规则 %s 的参数 %q 不是数字", name, param))
+		}
+		size, isLength := measure(v)
+		ok := size >= limit
+		word := "小于"
+		if name == "max" {
+			ok = size <= limit
+			word = "大于"
+		}
+		if isLength {
+			return ok, fmt.Sprintf("长度不能%s%s", word, param)
+		}
+		return ok, fmt.Sprintf("不能%s%s", word, param)
+	case "len":
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			panic(fmt.Sprintf("binding: 规则 len 的参数 %q 不是整数", param))
+		}
+		size, _ := measure(v)
+		return int(size) == n, fmt.Sprintf("长度必须为%s", param)
+	case "email":
+		return isEmail(v.String()), "必须是有效的邮箱地址"
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(param) {
+			if s == option {
+				return true, ""
+			}
+		}
+		return false, fmt.Sprintf("必须是 [%s] 中的一个", param)
+	}
+
+	rulesMu.RLock()
+	custom, ok := customRules[name]
+	rulesMu.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("binding: 未知的校验规则 %q", name))
+	}
+	message := custom.message
+	if strings.Contains(message, "%s") {
+		message = fmt.Sprintf(message, param)
+	}
+	return custom.fn(v, param), message
+}
+
+// measure 返回用于 min/max/len 比较的数值，isLength 表示比较的是长度
+func measure(v reflect.Value) (size float64, isLength bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	}
+	panic(fmt.Sprintf("binding: 类型 %s 不支持 min/max/len 规则", v.Type()))
+}
+
+// isEmail 简单的邮箱格式检查，不允许带显示名称
+func isEmail(s string) bool {
+	if s == "" {
+		return false
+	}
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s && strings.Contains(s[strings.LastIndex(s, "@"):], ".")
+}
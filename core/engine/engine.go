@@ -7,16 +7,18 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
-	"fmt"
 	"html/template"
-	"log"
 	"math/big"
 	"net"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/keyring"
+	"github.com/guyigood/gyweb/core/logger"
 	"github.com/guyigood/gyweb/core/middleware"
 	"github.com/guyigood/gyweb/core/router"
 )
@@ -24,10 +26,24 @@ import (
 // Engine 是框架的核心结构
 type Engine struct {
 	*RouterGroup
-	router        router.Router
-	groups        []*RouterGroup
-	htmlTemplates *template.Template
-	funcMap       template.FuncMap
+	router      router.Router
+	groups      []*RouterGroup
+	routes      []*route
+	pool        sync.Pool
+	namedRoutes map[string]*route
+	htmlRender  gyarn.HTMLRenderer
+	htmlDevMode bool
+	funcMap     template.FuncMap
+	upload      *gyarn.UploadConfig
+	proxy       *gyarn.ProxyConfig
+	keyRing     *keyring.Ring
+
+	// 服务器生命周期
+	serverConfig *ServerConfig
+	run          *serverRun // 当前一次运行，由 serverMu 保护
+	serverMu     sync.Mutex
+	onStart      []StartHook
+	onShutdown   []ShutdownHook
 }
 
 // RouterGroup 路由组
@@ -38,6 +54,16 @@ type RouterGroup struct {
 	engine      *Engine
 }
 
+// route 已注册的路由
+// handlers 只保存路由自身的处理器，组中间件在注册和 Use 时合并进路由器
+type route struct {
+	method   string
+	pattern  string
+	handlers []middleware.HandlerFunc
+	group    *RouterGroup
+	name     string
+}
+
 // TLSConfig TLS证书配置
 type TLSConfig struct {
 	CertFile string // 证书文件路径
@@ -48,72 +74,186 @@ type TLSConfig struct {
 func New() *Engine {
 	engine := &Engine{
 		router: router.New(),
+		proxy:  gyarn.DefaultProxyConfig(),
 	}
 	engine.RouterGroup = &RouterGroup{engine: engine}
 	engine.groups = []*RouterGroup{engine.RouterGroup}
+	engine.pool.New = func() interface{} {
+		return &gyarn.Context{}
+	}
 	return engine
 }
 
 // Group 创建路由组
-func (group *RouterGroup) Group(prefix string) *RouterGroup {
+// 可以同时传入该组的中间件，等价于创建后调用 Use；中间件切片会被复制，之后修改传入的切片不影响该组
+func (group *RouterGroup) Group(prefix string, middlewares ...middleware.HandlerFunc) *RouterGroup {
 	engine := group.engine
 	newGroup := &RouterGroup{
-		prefix: group.prefix + prefix,
-		parent: group,
-		engine: engine,
+		prefix:      group.prefix + prefix,
+		middlewares: append([]middleware.HandlerFunc(nil), middlewares...),
+		parent:      group,
+		engine:      engine,
 	}
 	engine.groups = append(engine.groups, newGroup)
 	return newGroup
 }
 
+// anyMethods Any 注册的全部请求方法
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodHead, http.MethodOptions,
+	http.MethodConnect, http.MethodTrace,
+}
+
 // Any 注册所有请求方法的路由
-func (group *RouterGroup) Any(pattern string, handler middleware.HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
-	group.addRoute("POST", pattern, handler)
-	group.addRoute("PUT", pattern, handler)
-	group.addRoute("DELETE", pattern, handler)
+func (group *RouterGroup) Any(pattern string, handlers ...middleware.HandlerFunc) *Route {
+	entries := make([]*route, 0, len(anyMethods))
+	for _, method := range anyMethods {
+		entries = append(entries, group.addRoute(method, pattern, handlers))
+	}
+	return group.newRoute(entries...)
 }
 
 // Use 添加中间件
+// 中间件只作用于本组及其子组注册的路由，在注册路由之后调用同样生效
 func (group *RouterGroup) Use(middlewares ...middleware.HandlerFunc) {
 	group.middlewares = append(group.middlewares, middlewares...)
+	engine := group.engine
+	for _, rt := range engine.routes {
+		if rt.group.isDescendantOf(group) {
+			engine.router.SetHandlers(rt.method, rt.pattern, rt.group.combineHandlers(rt.handlers)...)
+		}
+	}
 }
 
 // addRoute 添加路由
-func (group *RouterGroup) addRoute(method string, comp string, handler middleware.HandlerFunc) {
+func (group *RouterGroup) addRoute(method string, comp string, handlers []middleware.HandlerFunc) *route {
+	if len(handlers) == 0 {
+		panic("gyweb: 路由 " + method + " " + group.prefix + comp + " 至少需要一个处理函数")
+	}
 	pattern := group.prefix + comp
-	fmt.Printf("[DEBUG] 注册路由: %s %s\n", method, pattern)
-	group.engine.router.AddRoute(method, pattern, handler)
+	logger.Debug("注册路由", "method", method, "pattern", pattern)
+	group.engine.router.AddRoute(method, pattern, group.combineHandlers(handlers)...)
+	rt := &route{
+		method:   method,
+		pattern:  pattern,
+		handlers: handlers,
+		group:    group,
+	}
+	group.engine.routes = append(group.engine.routes, rt)
+	return rt
+}
+
+// combineHandlers 按从根组到当前组的顺序合并中间件，再追加路由处理器
+func (group *RouterGroup) combineHandlers(handlers []middleware.HandlerFunc) []middleware.HandlerFunc {
+	var chain []*RouterGroup
+	for g := group; g != nil; g = g.parent {
+		chain = append(chain, g)
+	}
+	merged := make([]middleware.HandlerFunc, 0, len(handlers))
+	for i := len(chain) - 1; i >= 0; i-- {
+		merged = append(merged, chain[i].middlewares...)
+	}
+	return append(merged, handlers...)
+}
+
+// isDescendantOf 判断当前组是否为 ancestor 本身或其子孙组
+func (group *RouterGroup) isDescendantOf(ancestor *RouterGroup) bool {
+	for g := group; g != nil; g = g.parent {
+		if g == ancestor {
+			return true
+		}
+	}
+	return false
 }
 
 // GET 注册 GET 请求
-func (group *RouterGroup) GET(pattern string, handler middleware.HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
+func (group *RouterGroup) GET(pattern string, handlers ...middleware.HandlerFunc) *Route {
+	return group.newRoute(group.addRoute("GET", pattern, handlers))
 }
 
 // POST 注册 POST 请求
-func (group *RouterGroup) POST(pattern string, handler middleware.HandlerFunc) {
-	group.addRoute("POST", pattern, handler)
+func (group *RouterGroup) POST(pattern string, handlers ...middleware.HandlerFunc) *Route {
+	return group.newRoute(group.addRoute("POST", pattern, handlers))
 }
 
 // PUT 注册 PUT 请求
-func (group *RouterGroup) PUT(pattern string, handler middleware.HandlerFunc) {
-	group.addRoute("PUT", pattern, handler)
+func (group *RouterGroup) PUT(pattern string, handlers ...middleware.HandlerFunc) *Route {
+	return group.newRoute(group.addRoute("PUT", pattern, handlers))
 }
 
 // DELETE 注册 DELETE 请求
-func (group *RouterGroup) DELETE(pattern string, handler middleware.HandlerFunc) {
-	group.addRoute("DELETE", pattern, handler)
+func (group *RouterGroup) DELETE(pattern string, handlers ...middleware.HandlerFunc) *Route {
+	return group.newRoute(group.addRoute("DELETE", pattern, handlers))
+}
+
+// PATCH 注册 PATCH 请求
+func (group *RouterGroup) PATCH(pattern string, handlers ...middleware.HandlerFunc) *Route {
+	return group.newRoute(group.addRoute("PATCH", pattern, handlers))
+}
+
+// HEAD 注册 HEAD 请求
+// 未显式注册 HEAD 时，会自动使用同路径的 GET 路由处理
+func (group *RouterGroup) HEAD(pattern string, handlers ...middleware.HandlerFunc) *Route {
+	return group.newRoute(group.addRoute("HEAD", pattern, handlers))
+}
+
+// OPTIONS 注册 OPTIONS 请求
+// 未显式注册 OPTIONS 时，会自动返回包含 Allow 头的 204 响应
+func (group *RouterGroup) OPTIONS(pattern string, handlers ...middleware.HandlerFunc) *Route {
+	return group.newRoute(group.addRoute("OPTIONS", pattern, handlers))
 }
 
 // SetFuncMap 设置模板函数
+// 模板中默认提供 url 函数用于按路由名称生成地址，见 Engine.URL
 func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
 	engine.funcMap = funcMap
 }
 
-// LoadHTMLGlob 加载 HTML 模板
-func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern))
+// SetUploadConfig 设置请求体大小、上传文件大小和类型限制以及存储后端
+//
+//	r.SetUploadConfig(&gyarn.UploadConfig{
+//		MaxBodySize:  50 << 20,
+//		MaxFileSize:  10 << 20,
+//		AllowedExts:  []string{".jpg", ".png", ".pdf"},
+//		AllowedTypes: []string{"image/*", "application/pdf"},
+//		Storage:      storage.NewLocal("./upload"),
+//	})
+func (engine *Engine) SetUploadConfig(config *gyarn.UploadConfig) {
+	engine.upload = config
+}
+
+// SetTrustedProxies 设置受信任的反向代理，支持 CIDR 和单个 IP
+// 只有来自这些地址的请求才会读取 Forwarded、X-Forwarded-For 等转发头来确定 ClientIP，
+// 不传参数表示不信任任何代理；默认只信任本机回环地址
+//
+//	r.SetTrustedProxies("10.0.0.0/8", "192.168.1.10")
+func (engine *Engine) SetTrustedProxies(cidrs ...string) error {
+	proxies, err := gyarn.ParseTrustedProxies(cidrs...)
+	if err != nil {
+		return err
+	}
+	engine.proxy.TrustedProxies = proxies
+	return nil
+}
+
+// SetTrustedHeaders 设置按顺序查找客户端 IP 的转发头，默认依次为 Forwarded、X-Forwarded-For、X-Real-Ip
+func (engine *Engine) SetTrustedHeaders(headers ...string) {
+	engine.proxy.TrustedHeaders = headers
+}
+
+// SetTrustedPlatform 设置部署平台提供的客户端 IP 请求头，如 gyarn.PlatformCloudflare
+// 该请求头优先于转发头且不检查代理地址，只应在确认请求一定经过该平台时设置
+func (engine *Engine) SetTrustedPlatform(header string) {
+	engine.proxy.TrustedPlatform = header
+}
+
+// SetKeyRing 设置签名和加密 cookie 使用的密钥环，见 gyarn.Context.SetSignedCookie、SetEncryptedCookie
+//
+//	ring, _ := keyring.New([]byte(os.Getenv("COOKIE_KEY")))
+//	r.SetKeyRing(ring)
+func (engine *Engine) SetKeyRing(ring *keyring.Ring) {
+	engine.keyRing = ring
 }
 
 // Static 设置静态文件服务
@@ -152,56 +292,133 @@ func (engine *Engine) StaticFile(relativePath, filepath string) *Engine {
 }
 
 // ServeHTTP 实现 http.Handler 接口
+// 上下文从对象池中获取，请求处理结束后归还
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	fmt.Printf("[DEBUG] 收到请求: %s %s\n", req.Method, req.URL.Path)
+	c := engine.pool.Get().(*gyarn.Context)
+	c.Reset(w, req)
+	c.HTMLRender = engine.htmlRender
+	c.Proxy = engine.proxy
+	c.KeyRing = engine.keyRing
+	if engine.upload != nil {
+		c.Upload = engine.upload
+		c.LimitRequestBody()
+	}
+	engine.handleHTTPRequest(c)
+	// 只设置了状态码而没有写入响应体时（如 204），在这里发送响应头
+	c.Writer.WriteHeaderNow()
+	engine.pool.Put(c)
+}
 
-	var middlewares []middleware.HandlerFunc
-	for _, group := range engine.groups {
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
-			middlewares = append(middlewares, group.middlewares...)
-		}
+// handleHTTPRequest 查找并执行处理链
+// 匹配到路由时执行注册时合并好的处理链（所属组及上级组的中间件 + 路由处理器），
+// 未匹配到路由时只执行引擎级（根组）中间件，再依次按 HEAD 回退、OPTIONS、405、404 处理
+func (engine *Engine) handleHTTPRequest(c *gyarn.Context) {
+	req := c.Request
+	debug := logger.Enabled(logger.LevelDebug)
+	if debug {
+		logger.Debug("收到请求", "method", req.Method, "path", req.URL.Path)
+	}
+
+	if engine.matchRoute(c, req.Method) {
+		c.Next()
+		return
+	}
+	// HEAD 请求自动使用 GET 路由，响应体由 net/http 丢弃
+	if req.Method == http.MethodHead && engine.matchRoute(c, http.MethodGet) {
+		c.Next()
+		return
 	}
 
-	c := gyarn.NewContext(w, req)
-	c.Handlers = middlewares
-
-	if node, params := engine.router.GetRoute(req.Method, req.URL.Path); node != nil {
-		fmt.Printf("[DEBUG] 找到路由节点: %s, Pattern: %s\n", req.URL.Path, node.Pattern)
-		c.Params = params
-		key := req.Method + "-" + node.Pattern
-		fmt.Printf("[DEBUG] 查找处理器 key: %s\n", key)
-		handlers := engine.router.GetHandlers(key)
-		fmt.Printf("[DEBUG] 找到 %d 个处理器\n", len(handlers))
-		c.Handlers = append(c.Handlers, handlers...)
+	c.Handlers = append(c.Handlers, engine.RouterGroup.middlewares...)
+	if allowed := engine.allowedMethods(req.URL.Path); len(allowed) > 0 {
+		c.SetHeader("Allow", strings.Join(allowed, ", "))
+		if req.Method == http.MethodOptions {
+			c.Handlers = append(c.Handlers, optionsHandler)
+		} else {
+			if debug {
+				logger.Debug("请求方法不允许", "method", req.Method, "path", req.URL.Path)
+			}
+			c.Handlers = append(c.Handlers, methodNotAllowedHandler)
+		}
 	} else {
-		if req.Method != "OPTIONS" {
-			fmt.Printf("[DEBUG] 未找到路由: %s %s\n", req.Method, req.URL.Path)
-			//c.Error(http.StatusNotFound, "404 NOT FOUND: %s\n"+c.Path)
-			c.Handlers = append(c.Handlers, func(c *gyarn.Context) {
-				c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
-			})
-			//return
+		if debug {
+			logger.Debug("未找到路由", "method", req.Method, "path", req.URL.Path)
 		}
+		c.Handlers = append(c.Handlers, notFoundHandler)
 	}
 
 	c.Next()
 }
 
+// optionsHandler 自动 OPTIONS 响应，Allow 头已在查找路由时设置
+func optionsHandler(c *gyarn.Context) {
+	c.Status(http.StatusNoContent)
+}
+
+// methodNotAllowedHandler 405 响应
+func methodNotAllowedHandler(c *gyarn.Context) {
+	c.String(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: %s\n", c.Method)
+}
+
+// notFoundHandler 404 响应
+func notFoundHandler(c *gyarn.Context) {
+	c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+}
+
+// matchRoute 按指定方法查找路由，找到时把路由参数和处理链设置到上下文中
+func (engine *Engine) matchRoute(c *gyarn.Context, method string) bool {
+	node := engine.router.Lookup(method, c.Request.URL.Path, c.Params)
+	if node == nil {
+		return false
+	}
+	c.Handlers = node.Handlers
+	c.Pattern = node.Pattern
+	if logger.Enabled(logger.LevelDebug) {
+		logger.Debug("找到路由", "method", method, "pattern", node.Pattern, "handlers", len(c.Handlers))
+	}
+	return true
+}
+
+// allowedMethods 返回路径可用的请求方法，包含隐式的 HEAD 和 OPTIONS
+func (engine *Engine) allowedMethods(path string) []string {
+	methods := engine.router.AllowedMethods(path)
+	if len(methods) == 0 {
+		return nil
+	}
+	hasMethod := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		hasMethod[method] = true
+	}
+	if hasMethod[http.MethodGet] && !hasMethod[http.MethodHead] {
+		methods = append(methods, http.MethodHead)
+	}
+	if !hasMethod[http.MethodOptions] {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
 // Run 启动 HTTP 服务器
+// 服务器通过 Shutdown 关闭时返回 nil
 func (engine *Engine) Run(addr string) (err error) {
-	log.Printf("Server is running on %s", addr)
-	return http.ListenAndServe(addr, engine)
+	logger.Info("Server is running", "addr", addr)
+	server := engine.newServer(addr, nil)
+	return engine.serve(server.ListenAndServe)
 }
 
 // RunTLS 启动 HTTPS 服务器
 func (engine *Engine) RunTLS(addr string, tlsConfig *TLSConfig) (err error) {
-	log.Printf("Server is running on https://%s", addr)
-	return http.ListenAndServeTLS(addr, tlsConfig.CertFile, tlsConfig.KeyFile, engine)
+	logger.Info("Server is running", "addr", "https://"+addr)
+	server := engine.newServer(addr, nil)
+	return engine.serve(func() error {
+		return server.ListenAndServeTLS(tlsConfig.CertFile, tlsConfig.KeyFile)
+	})
 }
 
 // RunAutoTLS 启动自动证书的 HTTPS 服务器（用于开发环境）
 func (engine *Engine) RunAutoTLS(addr string) (err error) {
-	log.Printf("Server is running on https://%s with self-signed certificate", addr)
+	logger.Info("Server is running with self-signed certificate", "addr", "https://"+addr)
 
 	// 创建自签名证书（仅用于开发环境）
 	certPEM, keyPEM, err := generateSelfSignedCert()
@@ -214,15 +431,13 @@ func (engine *Engine) RunAutoTLS(addr string) (err error) {
 		return err
 	}
 
-	server := &http.Server{
-		Addr:    addr,
-		Handler: engine,
-		TLSConfig: &tls.Config{
-			Certificates: []tls.Certificate{cert},
-		},
-	}
+	server := engine.newServer(addr, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	})
 
-	return server.ListenAndServeTLS("", "")
+	return engine.serve(func() error {
+		return server.ListenAndServeTLS("", "")
+	})
 }
 
 // generateSelfSignedCert 生成自签名证书（仅用于开发环境）
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+// benchWriter 丢弃所有输出的 ResponseWriter，避免 httptest.ResponseRecorder 本身的分配干扰结果
+type benchWriter struct {
+	header http.Header
+}
+
+func (w *benchWriter) Header() http.Header         { return w.header }
+func (w *benchWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *benchWriter) WriteHeader(int)             {}
+
+// newBenchEngine 创建带有全局中间件、分组中间件和参数路由的引擎
+func newBenchEngine() *Engine {
+	r := New()
+	noop := func(c *gyarn.Context) { c.Next() }
+	r.Use(noop)
+	api := r.Group("/api", noop)
+	api.GET("/ping", func(c *gyarn.Context) {})
+	api.GET("/db/page/:table", func(c *gyarn.Context) { _ = c.Param("table") })
+	api.GET("/files/*path", func(c *gyarn.Context) { _ = c.Param("path") })
+	return r
+}
+
+func benchmarkServeHTTP(b *testing.B, path string) {
+	r := newBenchEngine()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	w := &benchWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkServeHTTPStatic(b *testing.B) {
+	benchmarkServeHTTP(b, "/api/ping")
+}
+
+func BenchmarkServeHTTPParam(b *testing.B) {
+	benchmarkServeHTTP(b, "/api/db/page/login")
+}
+
+func BenchmarkServeHTTPCatchAll(b *testing.B) {
+	benchmarkServeHTTP(b, "/api/files/css/app.css")
+}
+
+func BenchmarkServeHTTPParallel(b *testing.B) {
+	r := newBenchEngine()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		req := httptest.NewRequest(http.MethodGet, "/api/db/page/login", nil)
+		w := &benchWriter{header: make(http.Header)}
+		for pb.Next() {
+			r.ServeHTTP(w, req)
+		}
+	})
+}
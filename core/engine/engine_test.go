@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/middleware"
+)
+
+// trace 返回把 name 追加到响应头 X-Trace 的中间件
+func trace(name string) middleware.HandlerFunc {
+	return func(c *gyarn.Context) {
+		c.Writer.Header().Add("X-Trace", name)
+		c.Next()
+	}
+}
+
+// do 发送请求并返回响应
+func do(r *Engine, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func traceOf(w *httptest.ResponseRecorder) string {
+	return strings.Join(w.Header().Values("X-Trace"), ",")
+}
+
+func respond(body string) middleware.HandlerFunc {
+	return func(c *gyarn.Context) { c.String(http.StatusOK, "%s", body) }
+}
+
+func TestGroupMiddlewareByRoute(t *testing.T) {
+	r := New()
+	r.Use(trace("global"))
+	api := r.Group("/api", trace("api"))
+	api.GET("/users", respond("users"))
+	// 前缀相同但不属于 /api 组的路由
+	r.GET("/apixyz", respond("apixyz"))
+	r.GET("/api-docs", respond("docs"))
+
+	tests := []struct {
+		path, trace, body string
+	}{
+		{"/api/users", "global,api", "users"},
+		{"/apixyz", "global", "apixyz"},
+		{"/api-docs", "global", "docs"},
+	}
+	for _, tt := range tests {
+		w := do(r, "GET", tt.path)
+		if got := traceOf(w); got != tt.trace || w.Body.String() != tt.body {
+			t.Errorf("%s: 中间件 %q 响应 %q，期望 %q %q", tt.path, got, w.Body.String(), tt.trace, tt.body)
+		}
+	}
+}
+
+func TestRouteHandlerChain(t *testing.T) {
+	r := New()
+	admin := r.Group("/admin", trace("admin"))
+	v1 := admin.Group("/v1", trace("v1"))
+	// 路由自身的多个处理函数排在组中间件之后
+	v1.GET("/orders", trace("auth"), respond("orders"))
+	v1.GET("/public", respond("public"))
+
+	if w := do(r, "GET", "/admin/v1/orders"); traceOf(w) != "admin,v1,auth" || w.Body.String() != "orders" {
+		t.Errorf("路由处理链: %q %q", traceOf(w), w.Body.String())
+	}
+	if w := do(r, "GET", "/admin/v1/public"); traceOf(w) != "admin,v1" {
+		t.Errorf("路由级中间件不应影响同组其他路由: %q", traceOf(w))
+	}
+}
+
+func TestUseAfterRoutes(t *testing.T) {
+	r := New()
+	api := r.Group("/api")
+	api.GET("/users", respond("users"))
+	sub := api.Group("/v2")
+	sub.GET("/items", respond("items"))
+	r.GET("/health", respond("ok"))
+
+	// 注册路由之后调用 Use，只作用于本组及子组
+	api.Use(trace("late"))
+	r.Use(trace("global"))
+
+	tests := []struct{ path, trace string }{
+		{"/api/users", "global,late"},
+		{"/api/v2/items", "global,late"},
+		{"/health", "global"},
+	}
+	for _, tt := range tests {
+		if got := traceOf(do(r, "GET", tt.path)); got != tt.trace {
+			t.Errorf("%s: 中间件 %q，期望 %q", tt.path, got, tt.trace)
+		}
+	}
+}
+
+func TestGroupCopiesMiddlewares(t *testing.T) {
+	r := New()
+	mws := make([]middleware.HandlerFunc, 1, 4)
+	mws[0] = trace("a")
+	g1 := r.Group("/g1", mws...)
+	// 调用方复用同一底层数组追加中间件，不能改变 g1 的处理链
+	_ = append(mws, trace("b"))
+	g1.Use(trace("c"))
+	g2 := r.Group("/g2", append(mws, trace("d"))...)
+	g1.GET("/x", respond("x"))
+	g2.GET("/x", respond("x"))
+
+	if got := traceOf(do(r, "GET", "/g1/x")); got != "a,c" {
+		t.Errorf("g1 中间件 %q，期望 a,c", got)
+	}
+	if got := traceOf(do(r, "GET", "/g2/x")); got != "a,d" {
+		t.Errorf("g2 中间件 %q，期望 a,d", got)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	r := New()
+	r.Use(trace("global"))
+	api := r.Group("/api", trace("api"))
+	api.GET("/users/:id", respond("get"))
+	api.PUT("/users/:id", respond("put"))
+
+	w := do(r, "POST", "/api/users/1")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("状态码 %d，期望 405", w.Code)
+	}
+	if got := w.Header().Get("Allow"); got != "GET, HEAD, OPTIONS, PUT" {
+		t.Errorf("Allow = %q", got)
+	}
+	if !strings.Contains(w.Body.String(), "POST") {
+		t.Errorf("响应应包含请求方法: %q", w.Body.String())
+	}
+	// 未匹配路由时只执行引擎级中间件
+	if got := traceOf(w); got != "global" {
+		t.Errorf("405 时执行的中间件 %q，期望 global", got)
+	}
+
+	if w := do(r, "POST", "/api/orders"); w.Code != http.StatusNotFound || w.Header().Get("Allow") != "" {
+		t.Errorf("路径不存在时应返回 404 且没有 Allow: %d %q", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	r := New()
+	r.GET("/", respond("home"))
+
+	w := do(r, "GET", "/missing")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("状态码 %d，期望 404", w.Code)
+	}
+	if got := w.Body.String(); got != "404 NOT FOUND: /missing\n" {
+		t.Errorf("404 响应为 %q", got)
+	}
+}
+
+func TestAutomaticOptions(t *testing.T) {
+	r := New()
+	api := r.Group("/api", trace("api"))
+	api.POST("/items", respond("post"))
+	api.OPTIONS("/custom", respond("custom"))
+
+	w := do(r, "OPTIONS", "/api/items")
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Fatalf("自动 OPTIONS 应返回 204 和空响应体: %d %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Allow"); got != "OPTIONS, POST" {
+		t.Errorf("Allow = %q", got)
+	}
+	// 显式注册的 OPTIONS 路由优先，并执行组中间件
+	if w := do(r, "OPTIONS", "/api/custom"); w.Body.String() != "custom" || traceOf(w) != "api" {
+		t.Errorf("显式 OPTIONS 路由: %q %q", w.Body.String(), traceOf(w))
+	}
+}
+
+func TestImplicitHead(t *testing.T) {
+	r := New()
+	api := r.Group("/api", trace("api"))
+	api.GET("/report", func(c *gyarn.Context) {
+		c.SetHeader("X-Total", "42")
+		c.String(http.StatusOK, "report body")
+	})
+	api.HEAD("/explicit", func(c *gyarn.Context) { c.Status(http.StatusAccepted) })
+	api.GET("/explicit", respond("get"))
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	head := func(path string) *http.Response {
+		resp, err := http.Head(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := head("/api/report")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Total") != "42" || resp.Header.Get("X-Trace") != "api" {
+		t.Fatalf("HEAD 应执行 GET 路由及其组中间件: %d %v", resp.StatusCode, resp.Header)
+	}
+	if resp.ContentLength != int64(len("report body")) {
+		t.Errorf("Content-Length = %d", resp.ContentLength)
+	}
+	// 不经过 net/http 时同样使用 GET 路由的状态码和响应头
+	w := do(r, "HEAD", "/api/report")
+	if w.Code != http.StatusOK || w.Header().Get("X-Total") != "42" {
+		t.Errorf("HEAD 回退到 GET: %d %v", w.Code, w.Header())
+	}
+	if resp := head("/api/explicit"); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("显式注册的 HEAD 路由应优先: %d", resp.StatusCode)
+	}
+}
+
+func TestAny(t *testing.T) {
+	r := New()
+	api := r.Group("/api", trace("api"))
+	api.Any("/echo", func(c *gyarn.Context) { c.String(http.StatusOK, "%s", c.Method) })
+
+	for _, method := range anyMethods {
+		w := do(r, method, "/api/echo")
+		if w.Code != http.StatusOK || traceOf(w) != "api" {
+			t.Errorf("%s: %d %q", method, w.Code, traceOf(w))
+		}
+		if method != http.MethodHead && w.Body.String() != method {
+			t.Errorf("%s: 响应 %q", method, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/guyigood/gyweb/core/logger"
+)
+
+// HTMLLayoutConfig 布局模式的模板配置
+// 每个页面与布局、公共片段单独组成一个模板集合，因此不同页面可以各自定义同名的 block，例如：
+//
+//	layouts/base.html:  <html><body>{{template "content" .}}</body></html>
+//	pages/index.html:   {{define "content"}}<h1>{{.Title}}</h1>{{template "footer.html" .}}{{end}}
+//	partials/footer.html
+type HTMLLayoutConfig struct {
+	// Layout 布局文件路径
+	Layout string
+	// Partials 公共片段的 glob 模式
+	Partials []string
+	// Pages 页面的 glob 模式，渲染时使用页面文件名，如 c.HTMLTemplate(200, "index.html", data)
+	Pages []string
+	// FS 不为 nil 时从该文件系统（如 embed.FS）加载，路径使用 / 分隔
+	FS fs.FS
+}
+
+// templateSource 模板文件来源
+type templateSource struct {
+	fsys     fs.FS    // 为 nil 时使用本地文件系统
+	patterns []string // glob 模式
+	files    []string // 显式指定的文件
+}
+
+// list 展开 glob 模式，返回全部模板文件
+func (s templateSource) list() ([]string, error) {
+	files := append([]string(nil), s.files...)
+	for _, pattern := range s.patterns {
+		var matches []string
+		var err error
+		if s.fsys != nil {
+			matches, err = fs.Glob(s.fsys, pattern)
+		} else {
+			matches, err = filepath.Glob(pattern)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("gyweb: 模板模式 %q 没有匹配到任何文件", pattern)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// read 读取模板文件内容
+func (s templateSource) read(name string) ([]byte, error) {
+	if s.fsys != nil {
+		return fs.ReadFile(s.fsys, name)
+	}
+	return os.ReadFile(name)
+}
+
+// baseName 模板名称，与 template.ParseFiles 一致使用文件名
+func (s templateSource) baseName(name string) string {
+	if s.fsys != nil {
+		return path.Base(name)
+	}
+	return filepath.Base(name)
+}
+
+// signature 返回文件列表及修改时间的摘要，用于开发模式检测变化
+// 嵌入文件系统不会变化，返回空字符串
+func (s templateSource) signature() string {
+	if s.fsys != nil {
+		return ""
+	}
+	files, err := s.list()
+	if err != nil {
+		return err.Error()
+	}
+	var sb strings.Builder
+	for _, file := range files {
+		sb.WriteString(file)
+		if info, err := os.Stat(file); err == nil {
+			sb.WriteString(fmt.Sprintf("@%d:%d;", info.ModTime().UnixNano(), info.Size()))
+		}
+	}
+	return sb.String()
+}
+
+// parseInto 把文件解析到模板集合 t 中
+func (s templateSource) parseInto(t *template.Template, files []string) error {
+	for _, file := range files {
+		content, err := s.read(file)
+		if err != nil {
+			return err
+		}
+		if _, err := t.New(s.baseName(file)).Parse(string(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// htmlSet 解析完成的模板
+type htmlSet struct {
+	tmpl   *template.Template            // 普通模式：所有模板位于同一集合
+	pages  map[string]*template.Template // 布局模式：页面名称到独立集合
+	layout string                        // 布局模式下执行的布局模板名称
+}
+
+// htmlRender 实现 gyarn.HTMLRenderer，支持开发模式下文件变化后自动重新解析
+type htmlRender struct {
+	mu        sync.RWMutex
+	source    templateSource
+	build     func() (*htmlSet, error)
+	set       *htmlSet
+	signature string
+	devMode   bool
+}
+
+// newHTMLRender 创建渲染器并立即解析一次模板，解析失败时 panic
+func newHTMLRender(source templateSource, devMode bool, build func() (*htmlSet, error)) *htmlRender {
+	set, err := build()
+	if err != nil {
+		panic(err)
+	}
+	render := &htmlRender{
+		source:  source,
+		build:   build,
+		set:     set,
+		devMode: devMode,
+	}
+	if devMode {
+		render.signature = source.signature()
+	}
+	return render
+}
+
+// Render 渲染指定名称的模板
+func (r *htmlRender) Render(w io.Writer, name string, data interface{}) error {
+	if r.devMode {
+		r.reloadIfChanged()
+	}
+	r.mu.RLock()
+	set := r.set
+	r.mu.RUnlock()
+
+	if set.pages != nil {
+		page, ok := set.pages[name]
+		if !ok {
+			return fmt.Errorf("gyweb: 未找到页面模板 %q", name)
+		}
+		return page.ExecuteTemplate(w, set.layout, data)
+	}
+	return set.tmpl.ExecuteTemplate(w, name, data)
+}
+
+// reloadIfChanged 模板文件有变化时重新解析，解析失败时保留旧模板
+func (r *htmlRender) reloadIfChanged() {
+	signature := r.source.signature()
+	r.mu.RLock()
+	changed := signature != r.signature
+	r.mu.RUnlock()
+	if !changed {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if signature == r.signature {
+		return
+	}
+	set, err := r.build()
+	r.signature = signature
+	if err != nil {
+		logger.Error("重新解析HTML模板失败", "error", err)
+		return
+	}
+	r.set = set
+	logger.Debug("HTML模板已重新解析")
+}
+
+// SetHTMLDevMode 设置模板开发模式
+// 开启后每次渲染前检查模板文件是否变化，有变化时重新解析，修改模板无需重启服务；
+// 需要在 LoadHTML 系列方法之前调用
+func (engine *Engine) SetHTMLDevMode(enable bool) {
+	engine.htmlDevMode = enable
+}
+
+// LoadHTMLGlob 加载 HTML 模板
+func (engine *Engine) LoadHTMLGlob(pattern string) {
+	engine.loadHTML(templateSource{patterns: []string{pattern}})
+}
+
+// LoadHTMLFiles 加载指定的 HTML 模板文件
+func (engine *Engine) LoadHTMLFiles(files ...string) {
+	engine.loadHTML(templateSource{files: files})
+}
+
+// LoadHTMLFS 从文件系统（如 embed.FS）加载 HTML 模板
+func (engine *Engine) LoadHTMLFS(fsys fs.FS, patterns ...string) {
+	engine.loadHTML(templateSource{fsys: fsys, patterns: patterns})
+}
+
+// loadHTML 以普通模式加载模板，所有模板位于同一集合，可以互相引用
+func (engine *Engine) loadHTML(source templateSource) {
+	engine.htmlRender = newHTMLRender(source, engine.htmlDevMode, func() (*htmlSet, error) {
+		files, err := source.list()
+		if err != nil {
+			return nil, err
+		}
+		tmpl := template.New("").Funcs(engine.templateFuncMap())
+		if err := source.parseInto(tmpl, files); err != nil {
+			return nil, err
+		}
+		return &htmlSet{tmpl: tmpl}, nil
+	})
+}
+
+// LoadHTMLLayout 以布局模式加载模板
+func (engine *Engine) LoadHTMLLayout(config HTMLLayoutConfig) {
+	patterns := append(append([]string{}, config.Partials...), config.Pages...)
+	signatureSource := templateSource{fsys: config.FS, files: []string{config.Layout}, patterns: patterns}
+
+	engine.htmlRender = newHTMLRender(signatureSource, engine.htmlDevMode, func() (*htmlSet, error) {
+		partials, err := templateSource{fsys: config.FS, patterns: config.Partials}.list()
+		if err != nil {
+			return nil, err
+		}
+		pageSource := templateSource{fsys: config.FS, patterns: config.Pages}
+		pages, err := pageSource.list()
+		if err != nil {
+			return nil, err
+		}
+
+		// 先解析布局和公共片段，再为每个页面克隆一份独立集合
+		base := template.New("").Funcs(engine.templateFuncMap())
+		if err := pageSource.parseInto(base, append([]string{config.Layout}, partials...)); err != nil {
+			return nil, err
+		}
+		set := &htmlSet{
+			pages:  make(map[string]*template.Template, len(pages)),
+			layout: pageSource.baseName(config.Layout),
+		}
+		for _, page := range pages {
+			tmpl, err := base.Clone()
+			if err != nil {
+				return nil, err
+			}
+			if err := pageSource.parseInto(tmpl, []string{page}); err != nil {
+				return nil, err
+			}
+			set.pages[pageSource.baseName(page)] = tmpl
+		}
+		return set, nil
+	})
+}
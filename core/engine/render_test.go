@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"embed"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+//go:embed testdata/templates
+var templatesFS embed.FS
+
+// render 通过路由渲染页面模板
+func render(t *testing.T, r *Engine, name string, data interface{}) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c := gyarn.NewContext(w, httptest.NewRequest("GET", "/", nil))
+	c.HTMLRender = r.htmlRender
+	c.HTMLTemplate(200, name, data)
+	c.Writer.WriteHeaderNow()
+	if w.Code != 200 {
+		t.Fatalf("渲染 %s 失败: %d %s", name, w.Code, w.Body.String())
+	}
+	return w.Body.String()
+}
+
+func TestLoadHTMLLayoutFS(t *testing.T) {
+	r := New()
+	r.GET("/", noop).Name("home")
+	r.LoadHTMLLayout(HTMLLayoutConfig{
+		FS:       templatesFS,
+		Layout:   "testdata/templates/layouts/base.html",
+		Partials: []string{"testdata/templates/partials/*.html"},
+		Pages:    []string{"testdata/templates/pages/*.html"},
+	})
+
+	// 每个页面各自定义 content，互不覆盖；未定义 title 时使用布局中的默认值
+	if got, want := render(t, r, "index.html", gyarn.H{"Title": "欢迎"}),
+		`<html><title>首页</title><body><h1>欢迎</h1><footer>/</footer></body></html>`; got != want {
+		t.Errorf("index.html = %q，期望 %q", got, want)
+	}
+	if got, want := render(t, r, "about.html", nil),
+		`<html><title>默认标题</title><body><p>关于</p></body></html>`; got != want {
+		t.Errorf("about.html = %q，期望 %q", got, want)
+	}
+
+	w := httptest.NewRecorder()
+	c := gyarn.NewContext(w, httptest.NewRequest("GET", "/", nil))
+	c.HTMLRender = r.htmlRender
+	c.HTMLTemplate(200, "missing.html", nil)
+	if c.LastError() == nil {
+		t.Error("页面不存在时应返回错误")
+	}
+}
+
+func TestLoadHTMLFS(t *testing.T) {
+	r := New()
+	r.GET("/", noop).Name("home")
+	r.LoadHTMLFS(templatesFS, "testdata/templates/partials/*.html")
+	if got := render(t, r, "footer.html", nil); got != "<footer>/</footer>" {
+		t.Errorf("footer.html = %q", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("模式没有匹配到文件时应 panic")
+		}
+	}()
+	r.LoadHTMLFS(templatesFS, "testdata/templates/none/*.html")
+}
+
+func TestHTMLDevModeReload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "page.html")
+	write := func(content string, mtime time.Time) {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(file, mtime, mtime)
+	}
+	now := time.Now()
+	write("v1 {{.}}", now)
+
+	r := New()
+	r.SetHTMLDevMode(true)
+	r.LoadHTMLGlob(filepath.Join(dir, "*.html"))
+	if got := render(t, r, "page.html", "a"); got != "v1 a" {
+		t.Fatalf("首次渲染 %q", got)
+	}
+
+	// 修改时间变化后重新解析
+	write("v2 {{.}}", now.Add(time.Second))
+	if got := render(t, r, "page.html", "a"); got != "v2 a" {
+		t.Fatalf("文件变化后应重新解析，实际 %q", got)
+	}
+
+	// 解析失败时保留旧模板
+	write("v3 {{.", now.Add(2*time.Second))
+	if got := render(t, r, "page.html", "a"); got != "v2 a" {
+		t.Fatalf("解析失败时应保留旧模板，实际 %q", got)
+	}
+
+	// 未开启开发模式时不重新解析
+	r2 := New()
+	write("v4", now.Add(3*time.Second))
+	r2.LoadHTMLGlob(filepath.Join(dir, "*.html"))
+	write("v5", now.Add(4*time.Second))
+	if got := render(t, r2, "page.html", nil); got != "v4" {
+		t.Fatalf("非开发模式不应重新解析，实际 %q", got)
+	}
+}
@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"text/tabwriter"
+
+	"github.com/guyigood/gyweb/core/middleware"
+)
+
+// RouteInfo 已注册路由的信息
+type RouteInfo struct {
+	Method      string // 请求方法
+	Path        string // 完整路由模式，如 /api/db/page/:table
+	Handler     string // 最终处理函数名称
+	Middlewares int    // 所属路由组（含上级组）的中间件数量
+	Handlers    int    // 路由自身的处理器数量（含单路由中间件）
+}
+
+// Routes 返回已注册的全部路由，按注册顺序排列
+func (engine *Engine) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(engine.routes))
+	for _, rt := range engine.routes {
+		routes = append(routes, RouteInfo{
+			Method:      rt.method,
+			Path:        rt.pattern,
+			Handler:     nameOfFunction(rt.handlers[len(rt.handlers)-1]),
+			Middlewares: len(rt.group.combineHandlers(nil)),
+			Handlers:    len(rt.handlers),
+		})
+	}
+	return routes
+}
+
+// PrintRoutes 以表格形式输出路由表
+func (engine *Engine) PrintRoutes(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER\tMIDDLEWARES")
+	for _, rt := range engine.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", rt.Method, rt.Path, rt.Handler, rt.Middlewares+rt.Handlers-1)
+	}
+	tw.Flush()
+}
+
+// nameOfFunction 获取函数的完整名称，如 main.Login
+func nameOfFunction(f middleware.HandlerFunc) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+func listUsers(c *gyarn.Context) {}
+
+func TestRoutes(t *testing.T) {
+	r := New()
+	r.Use(trace("global"))
+	api := r.Group("/api", trace("api"))
+	admin := api.Group("/admin", trace("admin"))
+	api.GET("/users", listUsers)
+	admin.DELETE("/users/:id", trace("audit"), listUsers)
+	r.GET("/health", noop)
+
+	want := []RouteInfo{
+		{Method: "GET", Path: "/api/users", Handler: "github.com/guyigood/gyweb/core/engine.listUsers", Middlewares: 2, Handlers: 1},
+		{Method: "DELETE", Path: "/api/admin/users/:id", Handler: "github.com/guyigood/gyweb/core/engine.listUsers", Middlewares: 3, Handlers: 2},
+		{Method: "GET", Path: "/health", Handler: "github.com/guyigood/gyweb/core/engine.noop", Middlewares: 1, Handlers: 1},
+	}
+	got := r.Routes()
+	if len(got) != len(want) {
+		t.Fatalf("路由数量 %d，期望 %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("路由 %d = %+v，期望 %+v", i, got[i], want[i])
+		}
+	}
+
+	// 注册路由后添加的组中间件同样计入
+	admin.Use(trace("late"))
+	if rt := r.Routes()[1]; rt.Middlewares != 4 {
+		t.Errorf("Use 之后中间件数量 %d，期望 4", rt.Middlewares)
+	}
+}
+
+func TestPrintRoutes(t *testing.T) {
+	r := New()
+	r.Use(trace("global"))
+	api := r.Group("/api", trace("api"))
+	api.POST("/users/:id", trace("auth"), listUsers)
+
+	var buf bytes.Buffer
+	r.PrintRoutes(&buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("输出行数 %d: %q", len(lines), buf.String())
+	}
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "METHOD PATH HANDLER MIDDLEWARES" {
+		t.Errorf("表头 %q", lines[0])
+	}
+	// 中间件数量包含组中间件和路由级中间件，不含最终处理函数
+	fields := strings.Fields(lines[1])
+	if strings.Join(fields, " ") != "POST /api/users/:id github.com/guyigood/gyweb/core/engine.listUsers 3" {
+		t.Errorf("路由行 %q", lines[1])
+	}
+}
@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/guyigood/gyweb/core/logger"
+)
+
+// ServerConfig HTTP服务器配置
+// 用于控制引擎内部 http.Server 的各项超时及优雅关闭等待时间
+type ServerConfig struct {
+	// ReadTimeout 读取整个请求（包括请求体）的超时时间
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout 读取请求头的超时时间
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout 写入响应的超时时间
+	WriteTimeout time.Duration
+	// IdleTimeout keep-alive 连接的空闲超时时间
+	IdleTimeout time.Duration
+	// MaxHeaderBytes 请求头最大字节数，0 表示使用标准库默认值
+	MaxHeaderBytes int
+	// ShutdownTimeout RunWithContext 收到退出信号后等待请求处理完成的最长时间
+	ShutdownTimeout time.Duration
+}
+
+// DefaultServerConfig 返回默认的服务器配置
+// 写超时默认不限制，以免影响文件下载、WebSocket 等长连接场景
+func DefaultServerConfig() *ServerConfig {
+	return &ServerConfig{
+		ReadTimeout:       30 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      0,
+		IdleTimeout:       120 * time.Second,
+		ShutdownTimeout:   15 * time.Second,
+	}
+}
+
+// StartHook 服务启动前执行的钩子，返回错误时中止启动
+type StartHook func() error
+
+// ShutdownHook 服务关闭时执行的钩子，ctx 的截止时间即关闭等待的截止时间
+type ShutdownHook func(ctx context.Context) error
+
+// SetServerConfig 设置服务器配置
+// 需要在 Run 系列方法之前调用
+func (engine *Engine) SetServerConfig(config *ServerConfig) {
+	if config == nil {
+		config = DefaultServerConfig()
+	}
+	engine.serverConfig = config
+}
+
+// OnStart 注册服务启动钩子
+// 钩子按注册顺序在开始监听端口之前执行
+func (engine *Engine) OnStart(hooks ...StartHook) {
+	engine.onStart = append(engine.onStart, hooks...)
+}
+
+// OnShutdown 注册服务关闭钩子
+// 钩子在 HTTP 服务停止接收新请求并处理完在途请求后，按注册的逆序执行，
+// 适合关闭 WebSocket Hub、刷新异步日志、关闭数据库连接等
+func (engine *Engine) OnShutdown(hooks ...ShutdownHook) {
+	engine.onShutdown = append(engine.onShutdown, hooks...)
+}
+
+// serverRun 一次 Run 的状态，每次启动服务时新建，保证 Shutdown 只对当前服务执行一次
+type serverRun struct {
+	server *http.Server
+	// closed Shutdown 已经开始，之后创建的服务不再监听
+	closed bool
+	once   sync.Once
+	err    error
+}
+
+// Server 返回引擎当前使用的 http.Server，服务未启动时返回 nil
+func (engine *Engine) Server() *http.Server {
+	engine.serverMu.Lock()
+	defer engine.serverMu.Unlock()
+	if engine.run == nil {
+		return nil
+	}
+	return engine.run.server
+}
+
+// RunWithContext 启动 HTTP 服务器，并在 ctx 结束时优雅关闭
+// 通常与 signal.NotifyContext 配合使用：
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//	r.RunWithContext(ctx, ":8080")
+func (engine *Engine) RunWithContext(ctx context.Context, addr string) error {
+	logger.Info("Server is running", "addr", addr)
+	server := engine.newServer(addr, nil)
+	return engine.serveWithContext(ctx, server.ListenAndServe)
+}
+
+// RunTLSWithContext 启动 HTTPS 服务器，并在 ctx 结束时优雅关闭
+func (engine *Engine) RunTLSWithContext(ctx context.Context, addr string, tlsConfig *TLSConfig) error {
+	logger.Info("Server is running", "addr", "https://"+addr)
+	server := engine.newServer(addr, nil)
+	return engine.serveWithContext(ctx, func() error {
+		return server.ListenAndServeTLS(tlsConfig.CertFile, tlsConfig.KeyFile)
+	})
+}
+
+// Shutdown 优雅关闭服务器
+// 停止接收新连接，等待在途请求处理完成（直到 ctx 结束），然后执行关闭钩子
+// 可以与 Run 系列方法并发调用；在服务开始监听之前调用时，随后启动的服务会立即返回
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	engine.serverMu.Lock()
+	run := engine.run
+	if run == nil {
+		// 服务尚未启动，记录关闭请求，由 newServer 接收
+		run = &serverRun{}
+		engine.run = run
+	}
+	engine.serverMu.Unlock()
+
+	run.once.Do(func() {
+		engine.serverMu.Lock()
+		run.closed = true
+		server := run.server
+		engine.serverMu.Unlock()
+
+		var err error
+		if server != nil {
+			err = server.Shutdown(ctx)
+		}
+		for i := len(engine.onShutdown) - 1; i >= 0; i-- {
+			if hookErr := engine.onShutdown[i](ctx); hookErr != nil {
+				logger.Error("shutdown hook error", "error", hookErr)
+				if err == nil {
+					err = hookErr
+				}
+			}
+		}
+		run.err = err
+	})
+	return run.err
+}
+
+// newServer 根据服务器配置创建 http.Server
+func (engine *Engine) newServer(addr string, tlsConfig *tls.Config) *http.Server {
+	config := engine.serverConfig
+	if config == nil {
+		config = DefaultServerConfig()
+	}
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           engine,
+		TLSConfig:         tlsConfig,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+	}
+
+	engine.serverMu.Lock()
+	defer engine.serverMu.Unlock()
+	if run := engine.run; run != nil && run.server == nil {
+		// 启动前已经调用过 Shutdown：归入该次关闭，已关闭时让 ListenAndServe 直接返回 ErrServerClosed
+		run.server = server
+		if run.closed {
+			server.Close()
+		}
+		return server
+	}
+	engine.run = &serverRun{server: server}
+	return server
+}
+
+// serve 执行启动钩子并阻塞监听，服务被 Shutdown 关闭时返回 nil
+// 调试模式下启动前会打印路由表
+func (engine *Engine) serve(listen func() error) error {
+	if logger.Enabled(logger.LevelDebug) {
+		engine.PrintRoutes(os.Stdout)
+	}
+	for _, hook := range engine.onStart {
+		if err := hook(); err != nil {
+			return err
+		}
+	}
+	if err := listen(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serveWithContext 启动服务，并在 ctx 结束时按 ShutdownTimeout 优雅关闭
+func (engine *Engine) serveWithContext(ctx context.Context, listen func() error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- engine.serve(listen)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	timeout := DefaultServerConfig().ShutdownTimeout
+	if engine.serverConfig != nil && engine.serverConfig.ShutdownTimeout > 0 {
+		timeout = engine.serverConfig.ShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logger.Info("Server is shutting down", "timeout", timeout)
+	shutdownErr := engine.Shutdown(shutdownCtx)
+	if err := <-errCh; err != nil {
+		return err
+	}
+	return shutdownErr
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// runAsync 在协程中启动服务，返回 Run 的结果
+func runAsync(r *Engine) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- r.Run("127.0.0.1:0") }()
+	return done
+}
+
+func waitRun(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run 返回 %v，期望 nil", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Shutdown 后 Run 没有返回")
+	}
+}
+
+func TestShutdownBeforeRun(t *testing.T) {
+	r := New()
+	hooks := 0
+	r.OnShutdown(func(ctx context.Context) error {
+		hooks++
+		return nil
+	})
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 启动前收到的关闭请求不能丢失
+	waitRun(t, runAsync(r))
+	if hooks != 1 {
+		t.Errorf("关闭钩子执行了 %d 次，期望 1 次", hooks)
+	}
+}
+
+func TestShutdownConcurrentWithRun(t *testing.T) {
+	r := New()
+	hooks := make(chan struct{}, 4)
+	r.OnShutdown(func(ctx context.Context) error {
+		hooks <- struct{}{}
+		return nil
+	})
+	done := runAsync(r)
+	// 与 Run 并发调用多次，只关闭一次
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() { errs <- r.Shutdown(context.Background()) }()
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitRun(t, done)
+	if len(hooks) != 1 {
+		t.Errorf("关闭钩子执行了 %d 次，期望 1 次", len(hooks))
+	}
+}
+
+func TestRunAgainAfterShutdown(t *testing.T) {
+	r := New()
+	done := runAsync(r)
+	for r.Server() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	first := r.Server()
+	r.Shutdown(context.Background())
+	waitRun(t, done)
+
+	// 再次启动时使用新的关闭状态
+	done = runAsync(r)
+	for r.Server() == first {
+		time.Sleep(time.Millisecond)
+	}
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	waitRun(t, done)
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/middleware"
 )
 
 // StaticConfig 静态文件服务配置
@@ -24,7 +25,8 @@ type StaticConfig struct {
 	Browse bool
 	// MaxAge 缓存时间（秒）
 	MaxAge int
-	// Compress 是否启用压缩
+	// Compress 是否启用压缩：客户端接受 gzip 且存在同名的 .gz 文件（如 app.js.gz）时直接返回该文件，
+	// 其余文件交给压缩中间件（middleware.Gzip）处理；关闭时压缩中间件不会压缩静态文件
 	Compress bool
 }
 
@@ -84,7 +86,9 @@ func Static(config *StaticConfig) gyarn.HandlerFunc {
 
 		// 设置压缩头
 		if config.Compress {
-			c.SetHeader("Vary", "Accept-Encoding")
+			c.Writer.Header().Add("Vary", "Accept-Encoding")
+		} else {
+			middleware.DisableCompression(c)
 		}
 
 		// 尝试打开文件
@@ -136,6 +140,12 @@ func Static(config *StaticConfig) gyarn.HandlerFunc {
 		contentType := getContentType(filepath.Ext(filePath))
 		c.SetHeader("Content-Type", contentType)
 
+		// 优先使用预压缩的文件
+		if config.Compress && serveGzipFile(c, http.Dir(absRoot), relativePath) {
+			c.Abort()
+			return
+		}
+
 		// 使用http.ServeFile提供文件服务
 		http.ServeFile(c.Writer, c.Request, filePath)
 		c.Abort()
@@ -179,6 +189,25 @@ func StaticFS(fs http.FileSystem, prefix string) gyarn.HandlerFunc {
 	}
 }
 
+// serveGzipFile 客户端接受 gzip 且存在 name+".gz" 时返回该文件，返回 false 表示未处理
+func serveGzipFile(c *gyarn.Context, fs http.FileSystem, name string) bool {
+	if middleware.NegotiateEncoding(c.GetHeader("Accept-Encoding"), middleware.EncodingGzip) == "" {
+		return false
+	}
+	file, err := fs.Open(name + ".gz")
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		return false
+	}
+	c.SetHeader("Content-Encoding", middleware.EncodingGzip)
+	http.ServeContent(c.Writer, c.Request, name, stat.ModTime(), file)
+	return true
+}
+
 // dirListHandler 目录浏览处理器
 // 生成目录列表的HTML页面
 func dirListHandler(root, dirPath string) gyarn.HandlerFunc {
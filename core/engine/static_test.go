@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/middleware"
+)
+
+func TestStaticPrecompressed(t *testing.T) {
+	dir := t.TempDir()
+	source := strings.Repeat("console.log('gyweb');\n", 100)
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(source))
+	zw.Close()
+	os.WriteFile(filepath.Join(dir, "app.js"), []byte(source), 0644)
+	os.WriteFile(filepath.Join(dir, "app.js.gz"), gz.Bytes(), 0644)
+
+	r := New()
+	r.Use(middleware.Gzip(gzip.DefaultCompression))
+	r.Static("/static", dir)
+	do := func(acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/static/app.js", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || !bytes.Equal(w.Body.Bytes(), gz.Bytes()) ||
+		w.Header().Get("Content-Type") != "application/javascript" {
+		t.Fatalf("应直接返回预压缩文件: %v", w.Header())
+	}
+	if vary := w.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Accept-Encoding" {
+		t.Fatalf("Vary = %v", vary)
+	}
+
+	w = do("")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != source {
+		t.Fatalf("不支持 gzip 的客户端应收到原文件: %v", w.Header())
+	}
+	// 没有预压缩文件时由压缩中间件压缩
+	os.Remove(filepath.Join(dir, "app.js.gz"))
+	w = do("gzip")
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil || w.Header().Get("Content-Length") != "" {
+		t.Fatalf("应由压缩中间件压缩: %v %v", err, w.Header())
+	}
+	if data, _ := io.ReadAll(zr); string(data) != source {
+		t.Fatal("解压后的内容不一致")
+	}
+}
+
+func TestStaticCompressDisabled(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "data.txt"), []byte(strings.Repeat("a", 4096)), 0644)
+
+	r := New()
+	r.Use(middleware.Gzip(gzip.DefaultCompression))
+	config := DefaultStaticConfig()
+	config.Root, config.Compress = dir, false
+	r.Use(Static(config))
+
+	req := httptest.NewRequest("GET", "/static/data.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.Len() != 4096 {
+		t.Fatalf("Compress 为 false 时不应压缩: %v", w.Header())
+	}
+}
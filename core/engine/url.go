@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+// Route 路由注册结果，用于链式设置路由属性
+type Route struct {
+	engine  *Engine
+	entries []*route
+}
+
+// newRoute 包装刚注册的路由
+func (group *RouterGroup) newRoute(entries ...*route) *Route {
+	return &Route{engine: group.engine, entries: entries}
+}
+
+// Name 为路由命名，用于 Engine.URL 反向生成地址
+// 名称重复时 panic
+func (r *Route) Name(name string) *Route {
+	engine := r.engine
+	if existing, ok := engine.namedRoutes[name]; ok {
+		panic(fmt.Sprintf("gyweb: 路由名称 %q 已被 %s %s 使用", name, existing.method, existing.pattern))
+	}
+	if engine.namedRoutes == nil {
+		engine.namedRoutes = make(map[string]*route)
+	}
+	for _, entry := range r.entries {
+		entry.name = name
+	}
+	engine.namedRoutes[name] = r.entries[0]
+	return r
+}
+
+// URL 根据路由名称生成地址
+// params 中与 :param、*wildcard 同名的值会填入路径并转义，其余的值作为查询参数追加；:param 的值包含 / 时返回错误，例如：
+//
+//	r.Group("/api/db").GET("/page/:table", dbcommon.Page).Name("db.page")
+//	r.URL("db.page", gyarn.H{"table": "login", "page": 2}) // /api/db/page/login?page=2
+func (engine *Engine) URL(name string, params gyarn.H) (string, error) {
+	rt, ok := engine.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("gyweb: 未找到名为 %q 的路由", name)
+	}
+
+	used := make(map[string]bool)
+	parts := strings.Split(rt.pattern, "/")
+	for i, part := range parts {
+		if part == "" || (part[0] != ':' && part[0] != '*') {
+			continue
+		}
+		key := part[1:]
+		value, ok := params[key]
+		if !ok {
+			if part[0] == '*' {
+				parts[i] = ""
+				continue
+			}
+			return "", fmt.Errorf("gyweb: 路由 %q 缺少参数 %q", name, key)
+		}
+		used[key] = true
+		if part[0] == ':' {
+			// 路由按解码后的路径匹配，含 / 的值即使转义也无法匹配回该路由
+			s := fmt.Sprint(value)
+			if strings.Contains(s, "/") {
+				return "", fmt.Errorf("gyweb: 路由 %q 的参数 %q 不能包含 /，多级路径请使用 *%s", name, key, key)
+			}
+			parts[i] = url.PathEscape(s)
+			continue
+		}
+		// 通配符可以包含多级路径，逐级转义并保留 /
+		segments := strings.Split(strings.TrimPrefix(fmt.Sprint(value), "/"), "/")
+		for j, segment := range segments {
+			segments[j] = url.PathEscape(segment)
+		}
+		parts[i] = strings.Join(segments, "/")
+	}
+	path := strings.Join(parts, "/")
+
+	query := url.Values{}
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if !used[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		query.Add(key, fmt.Sprint(params[key]))
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path, nil
+}
+
+// urlFunc 模板中使用的 url 函数，参数为路由名称和成对的键值，例如：
+//
+//	<a href="{{ url "db.page" "table" "login" }}">
+func (engine *Engine) urlFunc(name string, pairs ...interface{}) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("gyweb: url %q 的参数必须成对出现", name)
+	}
+	params := make(gyarn.H, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		params[fmt.Sprint(pairs[i])] = pairs[i+1]
+	}
+	return engine.URL(name, params)
+}
+
+// templateFuncMap 返回模板函数，内置 url 函数，SetFuncMap 设置的同名函数优先
+func (engine *Engine) templateFuncMap() template.FuncMap {
+	funcMap := template.FuncMap{
+		"url": engine.urlFunc,
+	}
+	for name, fn := range engine.funcMap {
+		funcMap[name] = fn
+	}
+	return funcMap
+}
@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+func noop(c *gyarn.Context) {}
+
+func TestURL(t *testing.T) {
+	r := New()
+	api := r.Group("/api/db")
+	api.GET("/page/:table", noop).Name("db.page")
+	api.GET("/users/:id/files/*path", noop).Name("user.file")
+	r.Any("/ping", noop).Name("ping")
+
+	tests := []struct {
+		name   string
+		route  string
+		params gyarn.H
+		want   string
+	}{
+		{"路径参数", "db.page", gyarn.H{"table": "login"}, "/api/db/page/login"},
+		{"转义路径参数", "db.page", gyarn.H{"table": "a b?c#d"}, "/api/db/page/a%20b%3Fc%23d"},
+		{"多余参数作为查询参数", "db.page", gyarn.H{"table": "login", "page": 2, "q": "a&b"}, "/api/db/page/login?page=2&q=a%26b"},
+		{"通配符保留多级路径", "user.file", gyarn.H{"id": 7, "path": "/docs/年报 2024.pdf"},
+			"/api/db/users/7/files/docs/%E5%B9%B4%E6%8A%A5%202024.pdf"},
+		{"通配符可以省略", "user.file", gyarn.H{"id": 7}, "/api/db/users/7/files/"},
+		{"Any 注册的路由", "ping", nil, "/ping"},
+	}
+	for _, tt := range tests {
+		got, err := r.URL(tt.route, tt.params)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: URL = %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestURLErrors(t *testing.T) {
+	r := New()
+	r.GET("/page/:table", noop).Name("db.page")
+
+	if _, err := r.URL("db.page", gyarn.H{"page": 1}); err == nil || !strings.Contains(err.Error(), `"table"`) {
+		t.Errorf("缺少路径参数时应返回错误，实际 %v", err)
+	}
+	if _, err := r.URL("db.page", gyarn.H{"table": "a/b"}); err == nil || !strings.Contains(err.Error(), "/") {
+		t.Errorf(":param 的值包含 / 时应返回错误，实际 %v", err)
+	}
+	if _, err := r.URL("missing", nil); err == nil {
+		t.Error("路由名称不存在时应返回错误")
+	}
+}
+
+func TestURLRoundTrip(t *testing.T) {
+	r := New()
+	r.GET("/page/:table", func(c *gyarn.Context) { c.String(200, "%s", c.Param("table")) }).Name("db.page")
+
+	// 生成的地址应能匹配回同一路由并还原参数
+	for _, value := range []string{"login", "a b", "订单?#%"} {
+		u, err := r.URL("db.page", gyarn.H{"table": value})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+		if w.Code != 200 || w.Body.String() != value {
+			t.Errorf("%q -> %s: %d %q", value, u, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRouteNameDuplicate(t *testing.T) {
+	r := New()
+	r.GET("/a", noop).Name("dup")
+	defer func() {
+		msg, _ := recover().(string)
+		if !strings.Contains(msg, `"dup"`) || !strings.Contains(msg, "GET /a") {
+			t.Fatalf("重复的路由名称应 panic 并指出已有路由，实际 %q", msg)
+		}
+	}()
+	r.POST("/b", noop).Name("dup")
+}
+
+func TestURLTemplateFunc(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "link.html"), []byte(`<a href="{{ url "db.page" "table" "a b" "page" 2 }}">`), 0644)
+
+	r := New()
+	r.GET("/page/:table", noop).Name("db.page")
+	r.LoadHTMLGlob(filepath.Join(dir, "*.html"))
+	r.GET("/link", func(c *gyarn.Context) { c.HTMLTemplate(200, "link.html", nil) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/link", nil))
+	if want := `<a href="/page/a%20b?page=2">`; w.Body.String() != want {
+		t.Fatalf("模板输出 %q，期望 %q", w.Body.String(), want)
+	}
+}
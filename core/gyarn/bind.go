@@ -0,0 +1,102 @@
+package gyarn
+
+import (
+	"net/http"
+
+	"github.com/guyigood/gyweb/core/binding"
+)
+
+// 表单相关的 MIME 类型
+const (
+	MIMEPOSTForm          = binding.MIMEPOSTForm
+	MIMEMultipartPOSTForm = binding.MIMEMultipartPOSTForm
+)
+
+// ContentType 返回请求的 MIME 类型，不含 charset 等参数
+func (c *Context) ContentType() string {
+	return binding.ContentType(c.Request.Header.Get("Content-Type"))
+}
+
+// ShouldBind 根据请求方法和 Content-Type 选择绑定器并校验 binding 标签：
+// GET 绑定查询参数，JSON/XML/YAML 绑定请求体，其余按表单处理
+//
+//	type LoginReq struct {
+//		Username string `json:"username" form:"username" binding:"required,max=32"`
+//		Email    string `json:"email" form:"email" binding:"omitempty,email"`
+//	}
+func (c *Context) ShouldBind(obj interface{}) error {
+	return c.ShouldBindWith(obj, binding.Default(c.Method, c.ContentType()))
+}
+
+// ShouldBindWith 使用指定的绑定器绑定并校验
+func (c *Context) ShouldBindWith(obj interface{}, b binding.Binding) error {
+	return b.Bind(c.Request, obj)
+}
+
+// BindJSON 绑定 JSON 数据到结构体并校验 binding 标签
+func (c *Context) BindJSON(obj interface{}) error {
+	return c.ShouldBindWith(obj, binding.JSON)
+}
+
+// ShouldBindJSON 绑定 JSON 数据到结构体（别名方法）
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	return c.BindJSON(obj)
+}
+
+// BindXML 绑定XML请求体并校验 binding 标签
+func (c *Context) BindXML(obj interface{}) error {
+	return c.ShouldBindWith(obj, binding.XML)
+}
+
+// BindYAML 绑定YAML请求体并校验 binding 标签
+func (c *Context) BindYAML(obj interface{}) error {
+	return c.ShouldBindWith(obj, binding.YAML)
+}
+
+// BindQuery 绑定查询参数，字段使用 form 标签，如 `form:"page_no,default=1" binding:"min=1"`
+func (c *Context) BindQuery(obj interface{}) error {
+	return c.ShouldBindWith(obj, binding.Query)
+}
+
+// BindForm 绑定查询参数和表单（含 multipart），字段使用 form 标签
+func (c *Context) BindForm(obj interface{}) error {
+	return c.ShouldBindWith(obj, binding.Form)
+}
+
+// BindHeader 绑定请求头，字段使用 header 标签，如 `header:"X-Token"`
+func (c *Context) BindHeader(obj interface{}) error {
+	return c.ShouldBindWith(obj, binding.Header)
+}
+
+// BindUri 绑定路由参数，字段使用 uri 标签，如 `uri:"id" binding:"required"`
+func (c *Context) BindUri(obj interface{}) error {
+	return binding.Uri(c.Params, obj)
+}
+
+// MustBind 使用 ShouldBind 绑定，失败时输出错误响应并中止后续处理，返回是否成功
+//
+//	var req LoginReq
+//	if !c.MustBind(&req) {
+//		return
+//	}
+func (c *Context) MustBind(obj interface{}) bool {
+	if err := c.ShouldBind(obj); err != nil {
+		c.BindError(err)
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// BindError 输出绑定错误：校验失败时 data 为字段级错误列表，其他情况为请求格式错误
+// 与 Error 一致，HTTP 状态码为 200，code 为 400
+//
+//	{"code":400,"message":"参数校验失败","data":[{"field":"username","rule":"required","message":"不能为空"}]}
+func (c *Context) BindError(err error) {
+	e := ToHTTPError(err)
+	if e.Status == http.StatusBadRequest {
+		c.ErrorWithData(e.Code, e.Message, e.Details)
+		return
+	}
+	c.renderError(e)
+}
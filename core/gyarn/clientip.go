@@ -0,0 +1,202 @@
+package gyarn
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// 常用的客户端 IP 请求头
+const (
+	HeaderForwarded     = "Forwarded"
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderXRealIP       = "X-Real-Ip"
+)
+
+// 常见平台在边缘节点设置的客户端 IP 请求头，可用于 ProxyConfig.TrustedPlatform
+const (
+	PlatformGoogleAppEngine = "X-Appengine-Remote-Addr"
+	PlatformCloudflare      = "CF-Connecting-IP"
+)
+
+// ProxyConfig 反向代理配置，决定 ClientIP 是否以及如何使用代理转发的请求头，通过 engine.SetTrustedProxies 等方法设置
+//
+// 只有直接连接的地址（RemoteIP）属于受信任代理时才会读取转发头；
+// 多级代理时从右向左跳过受信任代理，第一个不受信任的地址即为客户端 IP，客户端自己伪造的头部会被忽略
+type ProxyConfig struct {
+	// TrustedProxies 受信任代理的网段，为空表示不信任任何代理，ClientIP 始终等于 RemoteIP
+	TrustedProxies []*net.IPNet
+	// TrustedHeaders 按顺序查找的客户端 IP 请求头，支持 Forwarded（RFC 7239）、X-Forwarded-For 和 X-Real-Ip 等单值头
+	TrustedHeaders []string
+	// TrustedPlatform 部署平台设置的客户端 IP 请求头，如 PlatformGoogleAppEngine、PlatformCloudflare，
+	// 设置后优先使用且不检查代理地址，只应在确认请求一定经过该平台时设置
+	TrustedPlatform string
+}
+
+// DefaultProxyConfig 返回默认配置：只信任本机回环地址上的代理（如同机部署的 Nginx），
+// 依次查找 Forwarded、X-Forwarded-For、X-Real-Ip
+func DefaultProxyConfig() *ProxyConfig {
+	proxies, _ := ParseTrustedProxies("127.0.0.0/8", "::1/128")
+	return &ProxyConfig{
+		TrustedProxies: proxies,
+		TrustedHeaders: []string{HeaderForwarded, HeaderXForwardedFor, HeaderXRealIP},
+	}
+}
+
+var defaultProxyConfig = DefaultProxyConfig()
+
+// ParseTrustedProxies 解析受信任代理列表，支持 CIDR（"10.0.0.0/8"）和单个 IP（"192.168.1.10"）
+func ParseTrustedProxies(cidrs ...string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("gyarn: 无效的代理地址 %q", cidr)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				nets = append(nets, &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)})
+			} else {
+				nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+			}
+			continue
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("gyarn: 无效的代理网段 %q: %w", cidr, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// isTrusted 判断地址是否属于受信任代理
+func (config *ProxyConfig) isTrusted(ip net.IP) bool {
+	for _, n := range config.TrustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyConfig 返回当前请求的代理配置
+func (c *Context) proxyConfig() *ProxyConfig {
+	if c.Proxy != nil {
+		return c.Proxy
+	}
+	return defaultProxyConfig
+}
+
+// RemoteIP 返回直接连接的对端地址（Request.RemoteAddr 中的 IP），不读取任何请求头
+func (c *Context) RemoteIP() string {
+	addr := strings.TrimSpace(c.Request.RemoteAddr)
+	if ip, _, err := net.SplitHostPort(addr); err == nil {
+		return ip
+	}
+	return addr
+}
+
+// ClientIP 获取客户端IP地址
+// 只有 RemoteIP 属于受信任代理时才使用转发头，见 ProxyConfig；未配置任何代理时返回 RemoteIP
+func (c *Context) ClientIP() string {
+	config := c.proxyConfig()
+	if config.TrustedPlatform != "" {
+		if ip := parseIP(c.Request.Header.Get(config.TrustedPlatform)); ip != nil {
+			return ip.String()
+		}
+	}
+
+	remoteIP := c.RemoteIP()
+	remote := net.ParseIP(remoteIP)
+	if remote == nil || !config.isTrusted(remote) {
+		return remoteIP
+	}
+	for _, name := range config.TrustedHeaders {
+		values := c.Request.Header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		var hops []string
+		if strings.EqualFold(name, HeaderForwarded) {
+			hops = parseForwarded(values)
+		} else {
+			for _, v := range values {
+				hops = append(hops, strings.Split(v, ",")...)
+			}
+		}
+		if ip, ok := config.clientFromHops(hops); ok {
+			return ip
+		}
+	}
+	return remoteIP
+}
+
+// clientFromHops 从右向左跳过受信任代理，返回第一个不受信任的地址；遇到无法解析的地址时放弃该请求头
+func (config *ProxyConfig) clientFromHops(hops []string) (string, bool) {
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseIP(hops[i])
+		if ip == nil {
+			return "", false
+		}
+		if i == 0 || !config.isTrusted(ip) {
+			return ip.String(), true
+		}
+	}
+	return "", false
+}
+
+// parseForwarded 按 RFC 7239 解析 Forwarded 头，按顺序返回各节点的 for 参数
+//
+//	Forwarded: for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"
+func parseForwarded(values []string) []string {
+	var hops []string
+	for _, v := range values {
+		for _, element := range splitQuoted(v, ',') {
+			for _, pair := range splitQuoted(element, ';') {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "for") {
+					continue
+				}
+				hops = append(hops, strings.Trim(strings.TrimSpace(value), `"`))
+			}
+		}
+	}
+	return hops
+}
+
+// splitQuoted 按分隔符拆分，忽略引号中的分隔符
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseIP 解析转发头中的地址，支持带端口的形式（"1.2.3.4:80"、"[::1]:80"）；
+// unknown 和 RFC 7239 的混淆标识（"_hidden"）返回 nil
+func parseIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	return net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+}
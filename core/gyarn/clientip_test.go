@@ -0,0 +1,77 @@
+package gyarn
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8", "192.168.1.10", "2001:db8::/32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ProxyConfig{
+		TrustedProxies: proxies,
+		TrustedHeaders: []string{HeaderForwarded, HeaderXForwardedFor, HeaderXRealIP},
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		config  *ProxyConfig
+		want    string
+	}{
+		{"不受信任的来源忽略转发头", "203.0.113.9:1234", map[string]string{"X-Forwarded-For": "1.1.1.1"}, config, "203.0.113.9"},
+		{"受信任代理", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1"}, config, "1.1.1.1"},
+		{"跳过受信任的多级代理", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 2.2.2.2, 192.168.1.10"}, config, "2.2.2.2"},
+		{"全部为受信任代理时取最左侧", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.5, 10.0.0.6"}, config, "10.0.0.5"},
+		{"无效地址时使用下一个头", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "garbage", "X-Real-Ip": "3.3.3.3"}, config, "3.3.3.3"},
+		{"Forwarded 优先", "10.0.0.1:1234", map[string]string{
+			"Forwarded":       `for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"`,
+			"X-Forwarded-For": "1.1.1.1",
+		}, config, "192.0.2.60"},
+		{"Forwarded IPv6 带端口", "10.0.0.1:1234", map[string]string{"Forwarded": `for="[2001:db9::17]:4711"`}, config, "2001:db9::17"},
+		{"Forwarded 混淆标识", "10.0.0.1:1234", map[string]string{"Forwarded": "for=_hidden", "X-Real-Ip": "4.4.4.4"}, config, "4.4.4.4"},
+		{"IPv6 远端", "[2001:db8::1]:443", map[string]string{"X-Real-Ip": "5.5.5.5"}, config, "5.5.5.5"},
+		{"默认只信任回环地址", "127.0.0.1:80", map[string]string{"X-Real-Ip": "6.6.6.6"}, nil, "6.6.6.6"},
+		{"默认不信任其他地址", "192.168.0.2:80", map[string]string{"X-Real-Ip": "6.6.6.6"}, nil, "192.168.0.2"},
+		{"平台头", "203.0.113.9:1234", map[string]string{"CF-Connecting-IP": "7.7.7.7"}, &ProxyConfig{TrustedPlatform: PlatformCloudflare}, "7.7.7.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			c := NewContext(httptest.NewRecorder(), req)
+			c.Proxy = tt.config
+			if got := c.ClientIP(); got != tt.want {
+				t.Errorf("ClientIP() = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "127.0.0.1:80"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1")
+	c := NewContext(httptest.NewRecorder(), req)
+	if got := c.RemoteIP(); got != "127.0.0.1" {
+		t.Errorf("RemoteIP() = %q，期望 127.0.0.1", got)
+	}
+	if got := c.ClientIP(); got != "1.1.1.1" {
+		t.Errorf("ClientIP() = %q，期望 1.1.1.1", got)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if _, err := ParseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Error("无效网段应返回错误")
+	}
+	if _, err := ParseTrustedProxies("not-an-ip"); err == nil {
+		t.Error("无效地址应返回错误")
+	}
+}
@@ -0,0 +1,197 @@
+package gyarn
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Context 实现了 context.Context，截止时间和取消信号来自 Request.Context()，
+// 因此可以直接传给数据库、HTTP 客户端等接受 context.Context 的调用：
+//
+//	rows, err := db.WithContext(c).Table("user").All()
+//
+// 上下文在请求结束后会被回收复用，不能在处理函数返回后继续使用；
+// 需要交给后台 goroutine 时使用 c.Copy()
+var _ context.Context = (*Context)(nil)
+
+// Deadline 返回请求的截止时间
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if c.Request == nil {
+		return time.Time{}, false
+	}
+	return c.Request.Context().Deadline()
+}
+
+// Done 在客户端断开或请求被取消时关闭
+func (c *Context) Done() <-chan struct{} {
+	if c.Request == nil {
+		return nil
+	}
+	return c.Request.Context().Done()
+}
+
+// Err 返回请求被取消的原因
+func (c *Context) Err() error {
+	if c.Request == nil {
+		return nil
+	}
+	return c.Request.Context().Err()
+}
+
+// Value 字符串类型的 key 先在 Set 存储的数据中查找，找不到时再查找 Request.Context()
+func (c *Context) Value(key any) any {
+	if k, ok := key.(string); ok {
+		if value, exists := c.Get(k); exists {
+			return value
+		}
+	}
+	if c.Request == nil {
+		return nil
+	}
+	return c.Request.Context().Value(key)
+}
+
+// Copy 复制一份可以安全交给后台 goroutine 使用的上下文
+// 副本保留请求、路由参数和 Keys 的快照，不能再写出响应（写入的内容会被丢弃），也不会继续执行中间件；
+// 副本的取消信号仍来自原请求，需要在请求结束后继续执行时可使用 context.WithoutCancel(c.Copy())
+//
+//	cp := c.Copy()
+//	go func() {
+//		saveLog(cp, cp.ClientIP(), cp.GetInt("user_id"))
+//	}()
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		Request:    c.Request,
+		Path:       c.Path,
+		Pattern:    c.Pattern,
+		Method:     c.Method,
+		StatusCode: c.StatusCode,
+		HTMLRender: c.HTMLRender,
+		Upload:     c.Upload,
+		Proxy:      c.Proxy,
+		KeyRing:    c.KeyRing,
+		index:      len(c.Handlers),
+		aborted:    true,
+	}
+	cp.writermem.reset(discardWriter{header: c.Writer.Header().Clone()})
+	cp.writermem.status = c.Writer.Status()
+	cp.Writer = &cp.writermem
+
+	cp.Params = make(map[string]string, len(c.Params))
+	for k, v := range c.Params {
+		cp.Params[k] = v
+	}
+	c.mu.RLock()
+	cp.Keys = make(map[string]interface{}, len(c.Keys))
+	for k, v := range c.Keys {
+		cp.Keys[k] = v
+	}
+	c.mu.RUnlock()
+	return cp
+}
+
+// discardWriter 副本上下文使用的 ResponseWriter，丢弃所有写入
+type discardWriter struct {
+	header http.Header
+}
+
+func (w discardWriter) Header() http.Header         { return w.header }
+func (w discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w discardWriter) WriteHeader(int)             {}
+
+// GetString 获取字符串类型的值，不存在或类型不符时返回零值
+func (c *Context) GetString(key string) (s string) {
+	if v, ok := c.Get(key); ok {
+		s, _ = v.(string)
+	}
+	return
+}
+
+// GetBool 获取 bool 类型的值
+func (c *Context) GetBool(key string) (b bool) {
+	if v, ok := c.Get(key); ok {
+		b, _ = v.(bool)
+	}
+	return
+}
+
+// GetInt 获取 int 类型的值
+func (c *Context) GetInt(key string) (i int) {
+	if v, ok := c.Get(key); ok {
+		i, _ = v.(int)
+	}
+	return
+}
+
+// GetInt64 获取 int64 类型的值
+func (c *Context) GetInt64(key string) (i int64) {
+	if v, ok := c.Get(key); ok {
+		i, _ = v.(int64)
+	}
+	return
+}
+
+// GetUint 获取 uint 类型的值
+func (c *Context) GetUint(key string) (u uint) {
+	if v, ok := c.Get(key); ok {
+		u, _ = v.(uint)
+	}
+	return
+}
+
+// GetUint64 获取 uint64 类型的值
+func (c *Context) GetUint64(key string) (u uint64) {
+	if v, ok := c.Get(key); ok {
+		u, _ = v.(uint64)
+	}
+	return
+}
+
+// GetFloat64 获取 float64 类型的值
+func (c *Context) GetFloat64(key string) (f float64) {
+	if v, ok := c.Get(key); ok {
+		f, _ = v.(float64)
+	}
+	return
+}
+
+// GetTime 获取 time.Time 类型的值
+func (c *Context) GetTime(key string) (t time.Time) {
+	if v, ok := c.Get(key); ok {
+		t, _ = v.(time.Time)
+	}
+	return
+}
+
+// GetDuration 获取 time.Duration 类型的值
+func (c *Context) GetDuration(key string) (d time.Duration) {
+	if v, ok := c.Get(key); ok {
+		d, _ = v.(time.Duration)
+	}
+	return
+}
+
+// GetStringSlice 获取 []string 类型的值
+func (c *Context) GetStringSlice(key string) (ss []string) {
+	if v, ok := c.Get(key); ok {
+		ss, _ = v.([]string)
+	}
+	return
+}
+
+// GetStringMap 获取 map[string]interface{} 类型的值
+func (c *Context) GetStringMap(key string) (sm map[string]interface{}) {
+	if v, ok := c.Get(key); ok {
+		sm, _ = v.(map[string]interface{})
+	}
+	return
+}
+
+// GetStringMapString 获取 map[string]string 类型的值
+func (c *Context) GetStringMapString(key string) (sms map[string]string) {
+	if v, ok := c.Get(key); ok {
+		sms, _ = v.(map[string]string)
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package gyarn
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCopySurvivesReset(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/users/1", nil))
+	c.Params["id"] = "1"
+	c.Set("user", "alice")
+	cp := c.Copy()
+
+	// 模拟请求结束后上下文被回收并用于下一个请求
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest("POST", "/orders/9", nil))
+	c.Params["id"] = "9"
+	c.Set("user", "bob")
+
+	if cp.Path != "/users/1" || cp.Method != "GET" || cp.Param("id") != "1" || cp.GetString("user") != "alice" {
+		t.Errorf("副本被复用的上下文修改: %s %s id=%s user=%s", cp.Method, cp.Path, cp.Param("id"), cp.GetString("user"))
+	}
+	cp.JSON(200, H{"ignored": true})
+	if w.Body.Len() != 0 {
+		t.Errorf("副本的写入应被丢弃: %q", w.Body.String())
+	}
+}
+
+func TestTypedGetters(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	now := time.Now()
+	c.Set("string", "s")
+	c.Set("int", 1)
+	c.Set("int64", int64(2))
+	c.Set("float64", 3.5)
+	c.Set("bool", true)
+	c.Set("time", now)
+	c.Set("duration", time.Second)
+	c.Set("strings", []string{"a"})
+
+	if c.GetString("string") != "s" || c.GetInt("int") != 1 || c.GetInt64("int64") != 2 ||
+		c.GetFloat64("float64") != 3.5 || !c.GetBool("bool") || !c.GetTime("time").Equal(now) ||
+		c.GetDuration("duration") != time.Second || len(c.GetStringSlice("strings")) != 1 {
+		t.Error("类型匹配时应返回存储的值")
+	}
+
+	// 类型不符或不存在时返回零值
+	if c.GetString("int") != "" || c.GetInt("int64") != 0 || c.GetInt64("int") != 0 ||
+		c.GetUint("int") != 0 || c.GetFloat64("int") != 0 || c.GetBool("string") ||
+		!c.GetTime("string").IsZero() || c.GetDuration("int64") != 0 ||
+		c.GetStringSlice("string") != nil || c.GetStringMap("missing") != nil {
+		t.Error("类型不符时应返回零值")
+	}
+}
+
+type ctxKey struct{}
+
+func TestValue(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user", "from-request")
+	ctx = context.WithValue(ctx, "trace", "t1")
+	ctx = context.WithValue(ctx, ctxKey{}, "typed")
+	c := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	c.Set("user", "from-keys")
+
+	tests := []struct {
+		key  any
+		want any
+	}{
+		{"user", "from-keys"}, // Set 存储的值优先
+		{"trace", "t1"},       // 找不到时查找 Request.Context()
+		{ctxKey{}, "typed"},   // 非字符串 key 直接查找 Request.Context()
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		if got := c.Value(tt.key); got != tt.want {
+			t.Errorf("Value(%v) = %v，期望 %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestKeysConcurrent(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("k%d", j%10)
+				c.Set(key, i)
+				c.GetInt(key)
+				c.Value(key)
+			}
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			c.Copy()
+		}
+	}()
+	wg.Wait()
+
+	if _, ok := c.Get("k9"); !ok {
+		t.Error("并发写入后应能读到值")
+	}
+}
@@ -0,0 +1,96 @@
+package gyarn
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrNoKeyRing 未通过 engine.SetKeyRing 设置密钥环时使用签名或加密 cookie
+var ErrNoKeyRing = errors.New("gyarn: 未设置密钥环")
+
+// SetCookieWith 按 http.Cookie 设置 cookie，可以设置 SameSite、Expires 等 SetCookie 不支持的属性；Path 为空时使用 "/"
+//
+//	c.SetCookieWith(&http.Cookie{
+//		Name:     "token",
+//		Value:    token,
+//		Expires:  time.Now().Add(24 * time.Hour),
+//		HttpOnly: true,
+//		Secure:   true,
+//		SameSite: http.SameSiteLaxMode,
+//	})
+func (c *Context) SetCookieWith(cookie *http.Cookie) {
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	http.SetCookie(c.Writer, cookie)
+}
+
+// DeleteCookie 删除 cookie，path 和 domain 需要与设置时一致
+func (c *Context) DeleteCookie(name, path, domain string) {
+	c.SetCookieWith(&http.Cookie{
+		Name:    name,
+		Path:    path,
+		Domain:  domain,
+		MaxAge:  -1,
+		Expires: time.Unix(0, 0),
+	})
+}
+
+// SetSignedCookie 设置签名 cookie，客户端可以读取但无法篡改 cookie.Value
+func (c *Context) SetSignedCookie(cookie *http.Cookie) error {
+	if c.KeyRing == nil {
+		return ErrNoKeyRing
+	}
+	signed := *cookie
+	signed.Value = c.KeyRing.Sign(cookie.Name, []byte(cookie.Value))
+	c.SetCookieWith(&signed)
+	return nil
+}
+
+// SignedCookie 读取并校验签名 cookie，签名无效时返回 keyring.ErrInvalid，过期时返回 keyring.ErrExpired
+func (c *Context) SignedCookie(name string) (string, error) {
+	if c.KeyRing == nil {
+		return "", ErrNoKeyRing
+	}
+	value, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := c.KeyRing.Verify(name, value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// SetEncryptedCookie 设置加密 cookie，客户端既无法读取也无法篡改 cookie.Value
+func (c *Context) SetEncryptedCookie(cookie *http.Cookie) error {
+	if c.KeyRing == nil {
+		return ErrNoKeyRing
+	}
+	value, err := c.KeyRing.Encrypt(cookie.Name, []byte(cookie.Value))
+	if err != nil {
+		return err
+	}
+	encrypted := *cookie
+	encrypted.Value = value
+	c.SetCookieWith(&encrypted)
+	return nil
+}
+
+// EncryptedCookie 读取并解密加密 cookie，无法解密时返回 keyring.ErrInvalid
+func (c *Context) EncryptedCookie(name string) (string, error) {
+	if c.KeyRing == nil {
+		return "", ErrNoKeyRing
+	}
+	value, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := c.KeyRing.Decrypt(name, value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,165 @@
+package gyarn
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/guyigood/gyweb/core/binding"
+)
+
+// HTTPError 带 HTTP 状态码和业务码的错误，处理函数通过 c.AbortWithError 返回，由错误渲染器统一输出
+//
+//	if user == nil {
+//		c.AbortWithError(gyarn.ErrNotFound.WithMessage("用户不存在"))
+//		return
+//	}
+type HTTPError struct {
+	// Status HTTP 状态码
+	Status int `json:"-"`
+	// Code 业务码，对应 Response.Code
+	Code int `json:"code"`
+	// Message 返回给客户端的错误描述
+	Message string `json:"message"`
+	// Details 附加信息，如字段级校验错误，对应 Response.Data
+	Details interface{} `json:"details,omitempty"`
+	// Err 原始错误，只用于日志，不会返回给客户端
+	Err error `json:"-"`
+}
+
+// NewHTTPError 创建错误，业务码与 HTTP 状态码相同
+func NewHTTPError(status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Code: status, Message: message}
+}
+
+// 常用错误，With 系列方法返回副本，可以放心在此基础上修改
+var (
+	ErrBadRequest      = NewHTTPError(http.StatusBadRequest, "请求参数错误")
+	ErrUnauthorized    = NewHTTPError(http.StatusUnauthorized, "未授权访问")
+	ErrForbidden       = NewHTTPError(http.StatusForbidden, "禁止访问")
+	ErrNotFound        = NewHTTPError(http.StatusNotFound, "资源不存在")
+	ErrConflict        = NewHTTPError(http.StatusConflict, "资源冲突")
+	ErrTooManyRequests = NewHTTPError(http.StatusTooManyRequests, "请求过于频繁")
+	ErrInternalServer  = NewHTTPError(http.StatusInternalServerError, "服务器内部错误")
+)
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// WithCode 返回使用指定业务码的副本
+func (e *HTTPError) WithCode(code int) *HTTPError {
+	cp := *e
+	cp.Code = code
+	return &cp
+}
+
+// WithMessage 返回使用指定描述的副本
+func (e *HTTPError) WithMessage(message string) *HTTPError {
+	cp := *e
+	cp.Message = message
+	return &cp
+}
+
+// WithDetails 返回附带 details 的副本
+func (e *HTTPError) WithDetails(details interface{}) *HTTPError {
+	cp := *e
+	cp.Details = details
+	return &cp
+}
+
+// WithError 返回记录了原始错误的副本
+func (e *HTTPError) WithError(err error) *HTTPError {
+	cp := *e
+	cp.Err = err
+	return &cp
+}
+
+// ToHTTPError 把任意错误转换为 HTTPError：
+// HTTPError 原样返回，参数校验失败为 400 并附带字段级错误，请求体超出限制为 413，请求数据解析失败为 400，其余为 500
+func ToHTTPError(err error) *HTTPError {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he
+	}
+	var verrs binding.ValidationErrors
+	if errors.As(err, &verrs) {
+		return &HTTPError{Status: http.StatusBadRequest, Code: ErrCodeBadRequest, Message: "参数校验失败", Details: verrs, Err: err}
+	}
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return ErrRequestTooLarge.WithError(err)
+	}
+	if errors.Is(err, binding.ErrBind) {
+		return &HTTPError{Status: http.StatusBadRequest, Code: ErrCodeBadRequest, Message: "请求参数格式错误", Details: err.Error(), Err: err}
+	}
+	return ErrInternalServer.WithError(err)
+}
+
+// ErrorRenderer 把错误输出为响应，由错误处理中间件通过 SetErrorRenderer 安装
+type ErrorRenderer func(c *Context, err *HTTPError)
+
+// SetErrorRenderer 设置当前请求的错误渲染器，Error、BadRequest、AbortWithError 等方法都通过它输出
+func (c *Context) SetErrorRenderer(render ErrorRenderer) {
+	c.errorRenderer = render
+}
+
+// AddError 记录错误但不输出响应，错误处理中间件会在处理链结束后统一输出
+func (c *Context) AddError(err error) {
+	if err != nil {
+		c.Errors = append(c.Errors, err)
+	}
+}
+
+// LastError 返回最后记录的错误
+func (c *Context) LastError() error {
+	if len(c.Errors) == 0 {
+		return nil
+	}
+	return c.Errors[len(c.Errors)-1]
+}
+
+// AbortWithError 记录错误、中止后续处理并输出错误响应
+func (c *Context) AbortWithError(err error) {
+	if err == nil {
+		return
+	}
+	c.Abort()
+	c.AddError(err)
+	c.RenderError(err)
+}
+
+// RenderError 使用当前的错误渲染器输出错误，响应已经写出时忽略
+func (c *Context) RenderError(err error) {
+	c.renderError(ToHTTPError(err))
+}
+
+func (c *Context) renderError(e *HTTPError) {
+	if c.Writer.Written() {
+		return
+	}
+	if c.errorRenderer != nil {
+		c.errorRenderer(c, e)
+		return
+	}
+	RenderEnvelope(c, e)
+}
+
+// RenderEnvelope 默认的错误渲染器，输出 Response 结构，Details 放在 data 中
+func RenderEnvelope(c *Context, e *HTTPError) {
+	status := e.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, Response{
+		Code:    e.Code,
+		Message: e.Message,
+		Data:    e.Details,
+	})
+}
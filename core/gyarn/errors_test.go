@@ -0,0 +1,128 @@
+package gyarn
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/binding"
+)
+
+func TestToHTTPError(t *testing.T) {
+	verrs := binding.ValidationErrors{{Field: "name", Rule: "required"}}
+	custom := ErrNotFound.WithMessage("用户不存在")
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    int
+		message string
+	}{
+		{"HTTPError 原样返回", custom, http.StatusNotFound, http.StatusNotFound, "用户不存在"},
+		{"包装的 HTTPError", fmt.Errorf("查询用户: %w", custom), http.StatusNotFound, http.StatusNotFound, "用户不存在"},
+		{"参数校验失败", verrs, http.StatusBadRequest, ErrCodeBadRequest, "参数校验失败"},
+		{"请求体超出限制", &http.MaxBytesError{Limit: 10}, http.StatusRequestEntityTooLarge, http.StatusRequestEntityTooLarge, "请求体过大"},
+		{"请求数据解析失败", fmt.Errorf("%w: invalid character", binding.ErrBind), http.StatusBadRequest, ErrCodeBadRequest, "请求参数格式错误"},
+		{"其他错误", errors.New("dial tcp: connection refused"), http.StatusInternalServerError, http.StatusInternalServerError, "服务器内部错误"},
+	}
+	for _, tt := range tests {
+		e := ToHTTPError(tt.err)
+		if e.Status != tt.status || e.Code != tt.code || e.Message != tt.message {
+			t.Errorf("%s: 得到 %d/%d/%q，期望 %d/%d/%q", tt.name, e.Status, e.Code, e.Message, tt.status, tt.code, tt.message)
+		}
+	}
+
+	if e := ToHTTPError(verrs); !reflect.DeepEqual(e.Details, verrs) {
+		t.Errorf("参数校验失败应在 Details 中附带字段错误，实际 %v", e.Details)
+	}
+	if e := ToHTTPError(custom); e != custom {
+		t.Error("HTTPError 应原样返回，不复制")
+	}
+	// 5xx 的原始错误只用于日志，不能出现在 Message 和 Details 中
+	cause := errors.New("secret dsn")
+	if e := ToHTTPError(cause); e.Message != ErrInternalServer.Message || e.Details != nil || !errors.Is(e, cause) {
+		t.Errorf("500 错误不应暴露原始错误: %+v", e)
+	}
+}
+
+func TestHTTPErrorWithCopies(t *testing.T) {
+	e := ErrBadRequest.WithCode(40001).WithMessage("手机号格式错误").WithDetails("phone")
+	if ErrBadRequest.Code != http.StatusBadRequest || ErrBadRequest.Message != "请求参数错误" || ErrBadRequest.Details != nil {
+		t.Fatalf("With 系列方法不应修改原错误: %+v", ErrBadRequest)
+	}
+	if e.Status != http.StatusBadRequest || e.Code != 40001 || e.Message != "手机号格式错误" || e.Details != "phone" {
+		t.Fatalf("副本内容不正确: %+v", e)
+	}
+	cause := errors.New("cause")
+	if wrapped := ErrInternalServer.WithError(cause); !errors.Is(wrapped, cause) || wrapped.Error() != "服务器内部错误: cause" {
+		t.Fatalf("WithError 应保留原始错误: %v", wrapped)
+	}
+}
+
+// envelope 执行 fn 并解析默认错误渲染器输出的 Response
+func envelope(t *testing.T, fn func(c *Context)) (*httptest.ResponseRecorder, Response, *Context) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/", nil))
+	fn(c)
+	c.Writer.WriteHeaderNow()
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("响应不是 Response 结构: %v %q", err, w.Body.String())
+	}
+	return w, resp, c
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      func(c *Context)
+		status  int
+		code    int
+		message string
+	}{
+		{"Error 的 HTTP 状态码为 200", func(c *Context) { c.Error(10001, "余额不足") }, http.StatusOK, 10001, "余额不足"},
+		{"Error 默认业务码 500", func(c *Context) { c.Error(0, "失败") }, http.StatusOK, 500, "失败"},
+		{"BadRequest", func(c *Context) { c.BadRequest("") }, http.StatusOK, ErrCodeBadRequest, "请求参数错误"},
+		{"Fail 使用相同的 HTTP 状态码和业务码", func(c *Context) { c.Fail(http.StatusConflict, "重复提交") }, http.StatusConflict, http.StatusConflict, "重复提交"},
+		{"Unauthorized 返回 401", func(c *Context) { c.Unauthorized("") }, http.StatusUnauthorized, ErrCodeUnauthorized, "未授权访问"},
+		{"AbortWithError", func(c *Context) { c.AbortWithError(ErrForbidden) }, http.StatusForbidden, http.StatusForbidden, "禁止访问"},
+	}
+	for _, tt := range tests {
+		w, resp, _ := envelope(t, tt.fn)
+		if w.Code != tt.status || resp.Code != tt.code || resp.Message != tt.message {
+			t.Errorf("%s: 得到 %d %+v，期望 %d/%d/%q", tt.name, w.Code, resp, tt.status, tt.code, tt.message)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, MIMEJSON) {
+			t.Errorf("%s: Content-Type=%q", tt.name, ct)
+		}
+	}
+
+	w, resp, c := envelope(t, func(c *Context) {
+		c.AbortWithError(ToHTTPError(binding.ValidationErrors{{Field: "name", Rule: "required"}}))
+	})
+	if w.Code != http.StatusBadRequest || resp.Data == nil || !c.IsAborted() || c.LastError() == nil {
+		t.Fatalf("校验错误应返回 400 并在 data 中附带字段错误: %d %+v", w.Code, resp)
+	}
+	if _, _, c := envelope(t, func(c *Context) { c.Fail(http.StatusConflict, "x") }); !c.IsAborted() {
+		t.Fatal("Fail 应中止后续处理")
+	}
+}
+
+func TestRenderErrorAfterWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/", nil))
+	c.String(http.StatusOK, "partial")
+	c.AbortWithError(ErrInternalServer)
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Fatalf("响应已写出时不应再输出错误: %d %q", w.Code, w.Body.String())
+	}
+	if c.LastError() == nil {
+		t.Fatal("错误仍应记录到 c.Errors")
+	}
+}
@@ -1,15 +1,17 @@
 package gyarn
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
-	"net"
 	"net/http"
-	"os"
-	"strings"
+	"sync"
+
+	"github.com/guyigood/gyweb/core/keyring"
+	"github.com/guyigood/gyweb/core/logger"
 )
 
 // HandlerFunc 定义处理函数类型
@@ -18,21 +20,41 @@ type HandlerFunc func(*Context)
 // H 是一个便捷的 map 类型，用于 JSON 响应
 type H map[string]interface{}
 
+// HTMLRenderer HTML模板渲染器，由引擎在加载模板后注入到上下文
+type HTMLRenderer interface {
+	Render(w io.Writer, name string, data interface{}) error
+}
+
 // Context 封装了请求和响应
 type Context struct {
-	Writer     http.ResponseWriter
+	// Writer 包装后的响应，可通过 Writer.Status()、Writer.Size() 获取实际写出的状态码和字节数
+	Writer     ResponseWriter
 	Request    *http.Request
 	Path       string
+	Pattern    string // 匹配到的路由模式，如 /user/:id，未匹配到路由时为空
 	Method     string
 	Params     map[string]string // 路由参数
 	StatusCode int
 	Handlers   []HandlerFunc
 	index      int
 	aborted    bool
-	// 用于存储请求级别的数据
+	// Keys 请求级别的数据，请通过 Set/Get 及 GetXxx 访问，它们在锁的保护下并发安全；
+	// 直接读写 Keys 不加锁，其他 goroutine 同时调用 Set 时会产生数据竞争，保留导出只为兼容旧代码
 	Keys map[string]interface{}
-	// 新增：标记是否已经写入状态码
-	statusWritten bool
+	mu   sync.RWMutex
+	// writermem Writer 的底层实现，随上下文复用
+	writermem responseWriter
+	// HTMLRender 引擎加载的HTML模板，供 HTMLTemplate 使用
+	HTMLRender HTMLRenderer
+	// Upload 引擎设置的上传限制，为 nil 时使用 DefaultUploadConfig
+	Upload *UploadConfig
+	// Proxy 引擎设置的反向代理配置，为 nil 时使用 DefaultProxyConfig，见 ClientIP
+	Proxy *ProxyConfig
+	// KeyRing 引擎设置的密钥环，供签名和加密 cookie 使用
+	KeyRing *keyring.Ring
+	// Errors 处理过程中通过 AddError、AbortWithError 记录的错误
+	Errors        []error
+	errorRenderer ErrorRenderer
 }
 
 // Response 标准响应结构
@@ -44,34 +66,68 @@ type Response struct {
 
 // NewContext 创建新的上下文
 func NewContext(w http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
-		Writer:        w,
-		Request:       req,
-		Path:          req.URL.Path,
-		Method:        req.Method,
-		Params:        make(map[string]string), // 初始化路由参数map
-		StatusCode:    http.StatusOK,           // 设置默认状态码
-		Handlers:      make([]HandlerFunc, 0),  // 初始化处理器切片
-		index:         -1,
-		aborted:       false,
-		Keys:          make(map[string]interface{}), // 初始化Keys map
-		statusWritten: false,                        // 初始化状态码写入标志
+	c := &Context{
+		Request:    req,
+		Path:       req.URL.Path,
+		Method:     req.Method,
+		Params:     make(map[string]string), // 初始化路由参数map
+		StatusCode: http.StatusOK,           // 设置默认状态码
+		Handlers:   make([]HandlerFunc, 0),  // 初始化处理器切片
+		index:      -1,
+		aborted:    false,
+		Keys:       make(map[string]interface{}), // 初始化Keys map
+	}
+	c.writermem.reset(w)
+	c.Writer = &c.writermem
+	return c
+}
+
+// Reset 重置上下文以便复用，由引擎的上下文池在每次请求开始时调用
+// 上下文在请求结束后会被回收，需要在其他 goroutine 中使用时应先取出所需的值
+func (c *Context) Reset(w http.ResponseWriter, req *http.Request) {
+	c.writermem.reset(w)
+	c.Writer = &c.writermem
+	c.Request = req
+	c.Path = req.URL.Path
+	c.Pattern = ""
+	c.Method = req.Method
+	if c.Params == nil {
+		c.Params = make(map[string]string)
+	} else {
+		clear(c.Params)
 	}
+	c.StatusCode = http.StatusOK
+	c.Handlers = nil
+	c.index = -1
+	c.aborted = false
+	c.mu.Lock()
+	if c.Keys != nil {
+		clear(c.Keys)
+	}
+	c.mu.Unlock()
+	c.HTMLRender = nil
+	c.Upload = nil
+	c.Proxy = nil
+	c.KeyRing = nil
+	clear(c.Errors)
+	c.Errors = c.Errors[:0]
+	c.errorRenderer = nil
 }
 
-// Set 存储键值对
+// Set 存储键值对，可在多个 goroutine 中并发调用
 func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.Keys == nil {
 		c.Keys = make(map[string]interface{})
 	}
 	c.Keys[key] = value
 }
 
-// Get 获取存储的值
+// Get 获取存储的值，可在多个 goroutine 中并发调用
 func (c *Context) Get(key string) (value interface{}, exists bool) {
-	if c.Keys == nil {
-		return nil, false
-	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	value, exists = c.Keys[key]
 	return
 }
@@ -81,9 +137,7 @@ func (c *Context) MustGet(key string) interface{} {
 	if value, exists := c.Get(key); exists {
 		return value
 	}
-	fmt.Println("Key \"" + key + "\" does not exist")
-	return nil
-
+	panic("gyarn: Key \"" + key + "\" does not exist")
 }
 
 // Next 执行下一个中间件
@@ -126,10 +180,15 @@ func (c *Context) XML(code int, obj interface{}) {
 }
 
 // String 发送字符串响应
+// 传入 values 时按 fmt.Sprintf 格式化，否则原样输出 format
 func (c *Context) String(code int, format string, values ...interface{}) {
-	c.SetHeader("Content-Type", "text/plain")
+	c.SetHeader("Content-Type", "text/plain; charset=utf-8")
 	c.Status(code)
-	c.Writer.Write([]byte(format))
+	if len(values) > 0 {
+		fmt.Fprintf(c.Writer, format, values...)
+		return
+	}
+	io.WriteString(c.Writer, format)
 }
 
 // Data 发送数据响应，支持设置Content-Type
@@ -148,13 +207,32 @@ func (c *Context) HTML(code int, html string) {
 	c.Writer.Write([]byte(html))
 }
 
+// HTMLTemplate 使用引擎加载的模板渲染 HTML 响应
+// 模板先渲染到缓冲区，出错时记录日志并通过错误渲染器返回 500，不会输出半截页面，也不会把模板错误返回给客户端
+func (c *Context) HTMLTemplate(code int, name string, data interface{}) {
+	if c.HTMLRender == nil {
+		c.abortTemplateError(name, errors.New("HTML模板未加载，请先调用 LoadHTMLGlob 等方法"))
+		return
+	}
+	var buf bytes.Buffer
+	if err := c.HTMLRender.Render(&buf, name, data); err != nil {
+		c.abortTemplateError(name, fmt.Errorf("渲染模板 %s 失败: %w", name, err))
+		return
+	}
+	c.Data(code, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// abortTemplateError 记录模板错误并返回 500，错误详情只写入日志和 c.Errors
+func (c *Context) abortTemplateError(name string, err error) {
+	logger.Error("渲染模板失败", "template", name, "method", c.Method, "path", c.Path, "error", err)
+	c.AbortWithError(ErrInternalServer.WithError(err))
+}
+
 // Status 设置状态码
+// 状态码在第一次写入响应体时才发送，发送后再调用不会改变实际的响应状态码
 func (c *Context) Status(code int) {
 	c.StatusCode = code
-	if !c.statusWritten {
-		c.Writer.WriteHeader(code)
-		c.statusWritten = true
-	}
+	c.Writer.WriteHeader(code)
 }
 
 // SetHeader 设置响应头
@@ -188,8 +266,8 @@ func (c *Context) PostForm(key string) string {
 
 // Fail 返回错误响应
 func (c *Context) Fail(code int, err string) {
-	c.index = len(c.Handlers)
-	c.JSON(code, H{"error": err})
+	c.Abort()
+	c.renderError(&HTTPError{Status: code, Code: code, Message: err})
 }
 
 // GetHeader 获取请求头
@@ -197,12 +275,12 @@ func (c *Context) GetHeader(key string) string {
 	return c.Request.Header.Get(key)
 }
 
-// SetCookie 设置Cookie
+// SetCookie 设置Cookie，需要设置 SameSite、Expires 时使用 SetCookieWith
 func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
 	if path == "" {
 		path = "/"
 	}
-	http.SetCookie(c.Writer, &http.Cookie{
+	c.SetCookieWith(&http.Cookie{
 		Name:     name,
 		Value:    value,
 		MaxAge:   maxAge,
@@ -222,25 +300,6 @@ func (c *Context) Cookie(name string) (string, error) {
 	return cookie.Value, nil
 }
 
-// BindJSON 绑定 JSON 数据到结构体
-func (c *Context) BindJSON(obj interface{}) error {
-	return json.NewDecoder(c.Request.Body).Decode(obj)
-}
-
-// ShouldBindJSON 绑定 JSON 数据到结构体（别名方法）
-func (c *Context) ShouldBindJSON(obj interface{}) error {
-	return c.BindJSON(obj)
-}
-
-// BindXML 绑定XML请求体
-func (c *Context) BindXML(obj interface{}) error {
-	decoder := xml.NewDecoder(c.Request.Body)
-	if err := decoder.Decode(obj); err != nil {
-		return err
-	}
-	return nil
-}
-
 // Success 成功响应
 func (c *Context) Success(data interface{}) {
 	c.JSON(http.StatusOK, Response{
@@ -259,15 +318,10 @@ func (c *Context) SuccessWithMessage(message string, data interface{}) {
 	})
 }
 
-// Error 错误响应
+// Error 错误响应，HTTP 状态码为 200，code 为业务码
+// 与其他错误方法一样通过错误渲染器输出，安装错误处理中间件后格式保持一致
 func (c *Context) Error(code int, message string) {
-	if code == 0 {
-		code = 500 // 默认错误码
-	}
-	c.JSON(http.StatusOK, Response{
-		Code:    code,
-		Message: message,
-	})
+	c.ErrorWithData(code, message, nil)
 }
 
 // ErrorWithData 带数据的错误响应
@@ -275,10 +329,11 @@ func (c *Context) ErrorWithData(code int, message string, data interface{}) {
 	if code == 0 {
 		code = 500 // 默认错误码
 	}
-	c.JSON(http.StatusOK, Response{
+	c.renderError(&HTTPError{
+		Status:  http.StatusOK,
 		Code:    code,
 		Message: message,
-		Data:    data,
+		Details: data,
 	})
 }
 
@@ -305,8 +360,7 @@ func (c *Context) Unauthorized(message string) {
 	if message == "" {
 		message = "未授权访问"
 	}
-	http.Error(c.Writer, message, http.StatusUnauthorized)
-	//c.Error(ErrCodeUnauthorized, message)
+	c.renderError(&HTTPError{Status: http.StatusUnauthorized, Code: ErrCodeUnauthorized, Message: message})
 }
 
 // Forbidden 403错误响应
@@ -333,62 +387,11 @@ func (c *Context) InternalServerError(message string) {
 	c.Error(ErrCodeInternalServer, message)
 }
 
-// FormFile 获取上传的文件
-func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
-	if c.Request.MultipartForm == nil {
-		if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
-			return nil, err
-		}
-	}
-	f, fh, err := c.Request.FormFile(name)
-	if err != nil {
-		return nil, err
-	}
-	f.Close()
-	return fh, nil
-}
-
-func (c *Context) SaveUploadedFile(header *multipart.FileHeader, dst string) error {
-	f, err := header.Open()
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, f)
-	return err
-}
-
 // GetRawData 获取请求的原始数据
 func (c *Context) GetRawData() ([]byte, error) {
 	return io.ReadAll(c.Request.Body)
 }
 
-// ClientIP 获取客户端IP地址
-func (c *Context) ClientIP() string {
-	clientIP := c.Request.Header.Get("X-Forwarded-For")
-	clientIP = strings.TrimSpace(strings.Split(clientIP, ",")[0])
-	if clientIP == "" {
-		clientIP = strings.TrimSpace(c.Request.Header.Get("X-Real-Ip"))
-	}
-	if clientIP != "" {
-		return clientIP
-	}
-	if addr := c.Request.Header.Get("X-Appengine-Remote-Addr"); addr != "" {
-		return addr
-	}
-	if ip, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr)); err == nil {
-		return ip
-	}
-	return ""
-}
-
 // Header 设置响应头
 func (c *Context) Header(key, value string) {
 	c.Writer.Header().Set(key, value)
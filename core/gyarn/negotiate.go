@@ -0,0 +1,156 @@
+package gyarn
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Negotiate 内容协商配置，根据请求的 Accept 头选择响应格式
+type Negotiate struct {
+	// Offered 可提供的格式（MIME 类型），按优先级排列；为空时按 JSON、XML、YAML、HTML 的顺序取已设置的字段
+	Offered []string
+	// JSON JSON 响应数据
+	JSON interface{}
+	// XML XML 响应数据
+	XML interface{}
+	// YAML YAML 响应数据
+	YAML interface{}
+	// HTML HTML 模板名称，使用 HTMLData 渲染
+	HTML string
+	// HTMLData 渲染 HTML 模板的数据
+	HTMLData interface{}
+	// Data 各格式未单独设置数据时使用的默认数据
+	Data interface{}
+}
+
+// mimeAliases 与标准 MIME 类型等价的写法
+var mimeAliases = map[string]string{
+	MIMEXML2:                MIMEXML,
+	"application/yaml":      MIMEYAML,
+	"text/yaml":             MIMEYAML,
+	"text/x-yaml":           MIMEYAML,
+	"application/xhtml+xml": MIMEHTML,
+}
+
+// Negotiate 根据 Accept 头选择格式并发送响应，没有可接受的格式时返回 406
+func (c *Context) Negotiate(code int, config Negotiate) {
+	offered := config.Offered
+	if len(offered) == 0 {
+		if config.JSON != nil || config.Data != nil {
+			offered = append(offered, MIMEJSON)
+		}
+		if config.XML != nil || config.Data != nil {
+			offered = append(offered, MIMEXML)
+		}
+		if config.YAML != nil || config.Data != nil {
+			offered = append(offered, MIMEYAML)
+		}
+		if config.HTML != "" {
+			offered = append(offered, MIMEHTML)
+		}
+	}
+
+	switch c.NegotiateFormat(offered...) {
+	case MIMEJSON:
+		c.JSON(code, pick(config.JSON, config.Data))
+	case MIMEXML:
+		c.XML(code, pick(config.XML, config.Data))
+	case MIMEYAML:
+		c.YAML(code, pick(config.YAML, config.Data))
+	case MIMEHTML:
+		c.HTMLTemplate(code, config.HTML, pick(config.HTMLData, config.Data))
+	default:
+		c.String(http.StatusNotAcceptable, "406 NOT ACCEPTABLE: 支持的格式为 %s", strings.Join(offered, ", "))
+	}
+}
+
+// NegotiateFormat 根据 Accept 头从 offered 中选择最合适的格式
+// 每个格式取匹配它的最具体一项（完整类型优先于 type/*，type/* 优先于 */*）的 q 值，q=0 表示明确拒绝；
+// 选择 q 值最高的格式，q 值相同时按 Accept 中的先后顺序，再按 offered 的顺序。
+// 没有 Accept 头时返回第一个，都不可接受时返回空字符串
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	header := c.GetHeader("Accept")
+	if strings.TrimSpace(header) == "" {
+		return offered[0]
+	}
+	accepts := parseAccept(header)
+	best, bestQ, bestIndex := "", 0.0, 0
+	for _, offer := range offered {
+		q, index := acceptQuality(accepts, offer)
+		if q > bestQ || (q == bestQ && q > 0 && index < bestIndex) {
+			best, bestQ, bestIndex = offer, q, index
+		}
+	}
+	return best
+}
+
+// acceptRange Accept 头中的一项
+type acceptRange struct {
+	mime string
+	q    float64
+}
+
+// parseAccept 按原顺序解析 Accept 头，保留 q=0 的项用于排除
+func parseAccept(header string) []acceptRange {
+	var accepts []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mime := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mime == "" {
+			continue
+		}
+		if alias, ok := mimeAliases[mime]; ok {
+			mime = alias
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && v >= 0 && v <= 1 {
+					q = v
+				}
+			}
+		}
+		accepts = append(accepts, acceptRange{mime: mime, q: q})
+	}
+	return accepts
+}
+
+// acceptQuality 返回 offer 的 q 值及决定该值的项在 Accept 中的位置，没有匹配项时 q 为 0
+func acceptQuality(accepts []acceptRange, offer string) (float64, int) {
+	q, index, specificity := 0.0, len(accepts), 0
+	for i, accept := range accepts {
+		if s := mimeSpecificity(accept.mime, offer); s > specificity {
+			q, index, specificity = accept.q, i, s
+		}
+	}
+	return q, index
+}
+
+// mimeSpecificity 判断 Accept 中的类型是否接受 offer，返回匹配的具体程度：
+// 0 不匹配，1 为 */*，2 为 type/*，3 为完整类型
+func mimeSpecificity(accept, offer string) int {
+	switch {
+	case accept == offer:
+		return 3
+	case accept == "*/*":
+		return 1
+	case strings.HasSuffix(accept, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(accept, "*")):
+		return 2
+	}
+	return 0
+}
+
+// pick 返回第一个非 nil 的值
+func pick(values ...interface{}) interface{} {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package gyarn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	offered := []string{MIMEJSON, MIMEXML, MIMEHTML}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", MIMEJSON},
+		{"application/xml", MIMEXML},
+		{"text/xml", MIMEXML},
+		{"text/html, application/json", MIMEHTML},
+		{"application/json;q=0.5, application/xml;q=0.9", MIMEXML},
+		{"text/*", MIMEHTML},
+		{"*/*", MIMEJSON},
+		// 完整类型优先于通配
+		{"*/*;q=0.1, application/xml", MIMEXML},
+		{"application/*;q=0.2, application/json;q=0.8", MIMEJSON},
+		// q=0 表示明确拒绝
+		{"application/json;q=0", ""},
+		{"application/json;q=0, */*", MIMEXML},
+		{"application/*;q=0, */*", MIMEHTML},
+		{"image/png", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		c := NewContext(httptest.NewRecorder(), req)
+		if got := c.NegotiateFormat(offered...); got != tt.want {
+			t.Errorf("Accept %q 选择 %q，期望 %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept      string
+		code        int
+		contentType string
+		body        string
+	}{
+		{"", http.StatusOK, MIMEJSON, `{"id":1}`},
+		{"application/xml;q=0.9, application/json;q=0.1", http.StatusOK, MIMEXML, "<id>1</id>"},
+		{"application/json;q=0, */*", http.StatusOK, MIMEXML, "<id>1</id>"},
+		{"application/json;q=0", http.StatusNotAcceptable, "text/plain", "406 NOT ACCEPTABLE"},
+	}
+	type item struct {
+		ID int `json:"id" xml:"id"`
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		c := NewContext(w, req)
+		c.Negotiate(http.StatusOK, Negotiate{Offered: []string{MIMEJSON, MIMEXML}, Data: item{ID: 1}})
+
+		if w.Code != tt.code {
+			t.Errorf("Accept %q 状态码为 %d，期望 %d", tt.accept, w.Code, tt.code)
+		}
+		if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.contentType) {
+			t.Errorf("Accept %q Content-Type=%q，期望 %s", tt.accept, got, tt.contentType)
+		}
+		if !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("Accept %q 响应为 %q，期望包含 %q", tt.accept, w.Body.String(), tt.body)
+		}
+	}
+}
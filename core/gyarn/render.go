@@ -0,0 +1,114 @@
+package gyarn
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 常用的 MIME 类型
+const (
+	MIMEJSON  = "application/json"
+	MIMEXML   = "application/xml"
+	MIMEXML2  = "text/xml"
+	MIMEYAML  = "application/x-yaml"
+	MIMEHTML  = "text/html"
+	MIMEPlain = "text/plain"
+	MIMEJSONP = "application/javascript"
+)
+
+// render 先序列化再写响应，序列化失败时返回 500 而不是输出半截内容
+func (c *Context) render(code int, contentType string, body []byte, err error) {
+	if err != nil {
+		c.String(http.StatusInternalServerError, "序列化响应失败: %v", err)
+		return
+	}
+	c.Data(code, contentType, body)
+}
+
+// PureJSON 发送 JSON 响应，不转义 <、>、& 等 HTML 字符
+func (c *Context) PureJSON(code int, obj interface{}) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(obj)
+	c.render(code, MIMEJSON+"; charset=utf-8", buf.Bytes(), err)
+}
+
+// IndentedJSON 发送带缩进的 JSON 响应，便于调试时阅读
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	body, err := json.MarshalIndent(obj, "", "    ")
+	c.render(code, MIMEJSON+"; charset=utf-8", body, err)
+}
+
+// AsciiJSON 发送只包含 ASCII 字符的 JSON 响应，非 ASCII 字符转义为 \uXXXX
+func (c *Context) AsciiJSON(code int, obj interface{}) {
+	body, err := json.Marshal(obj)
+	if err == nil {
+		body = asciiEscape(body)
+	}
+	c.render(code, MIMEJSON, body, err)
+}
+
+// jsonpCallbackPattern 合法的 JSONP 回调名：JavaScript 标识符，允许用 . 访问属性，如 jQuery123.cb
+var jsonpCallbackPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$.]*$`)
+
+// JSONP 发送 JSONP 响应，回调函数名取自查询参数 callback，未提供时等同于 JSON
+// 回调名不是合法的 JavaScript 标识符时返回 400，不输出任何脚本
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if callback == "" {
+		c.JSON(code, obj)
+		return
+	}
+	// 回调名来自客户端，原样拼进脚本，只能通过白名单校验防止注入（转义无法阻止 alert(1);x 这类输入）
+	if !jsonpCallbackPattern.MatchString(callback) {
+		c.AbortWithError(ErrBadRequest.WithMessage("callback 参数无效"))
+		return
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		c.render(code, "", nil, err)
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString("/**/ typeof " + callback + " === 'function' && ")
+	buf.WriteString(callback)
+	buf.WriteByte('(')
+	buf.Write(body)
+	buf.WriteString(");")
+	c.Data(code, MIMEJSONP+"; charset=utf-8", buf.Bytes())
+}
+
+// YAML 发送 YAML 响应
+func (c *Context) YAML(code int, obj interface{}) {
+	body, err := yaml.Marshal(obj)
+	c.render(code, MIMEYAML+"; charset=utf-8", body, err)
+}
+
+// asciiEscape 把 JSON 中的非 ASCII 字符转义为 \uXXXX
+func asciiEscape(body []byte) []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(body))
+	for len(body) > 0 {
+		r, size := utf8.DecodeRune(body)
+		body = body[size:]
+		if r < utf8.RuneSelf {
+			buf.WriteByte(byte(r))
+			continue
+		}
+		if r > 0xFFFF {
+			// 超出基本多文种平面的字符使用代理对表示
+			r -= 0x10000
+			fmt.Fprintf(&buf, "\\u%04x\\u%04x", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+			continue
+		}
+		fmt.Fprintf(&buf, "\\u%04x", r)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,122 @@
+package gyarn
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestJSONP(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/data?callback=jQuery123.cb", nil))
+	c.JSONP(http.StatusOK, H{"id": 1})
+
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, MIMEJSONP) {
+		t.Errorf("Content-Type=%q，期望 %s", got, MIMEJSONP)
+	}
+	want := `/**/ typeof jQuery123.cb === 'function' && jQuery123.cb({"id":1});`
+	if w.Body.String() != want {
+		t.Errorf("响应为 %q，期望 %q", w.Body.String(), want)
+	}
+}
+
+func TestJSONPInvalidCallback(t *testing.T) {
+	for _, callback := range []string{
+		"alert(document.cookie);x",
+		"cb;alert(1)",
+		"1cb",
+		"cb</script>",
+		"a b",
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/data", nil)
+		req.URL.RawQuery = url.Values{"callback": {callback}}.Encode()
+		c := NewContext(w, req)
+		c.JSONP(http.StatusOK, H{"id": 1})
+		c.Writer.WriteHeaderNow()
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("callback=%q 状态码为 %d，期望 400", callback, w.Code)
+		}
+		if strings.Contains(w.Body.String(), "alert") || strings.Contains(w.Body.String(), "typeof") {
+			t.Errorf("callback=%q 不应输出脚本: %s", callback, w.Body.String())
+		}
+		if !c.IsAborted() {
+			t.Errorf("callback=%q 应中止后续处理", callback)
+		}
+	}
+}
+
+func TestJSONPWithoutCallback(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/data", nil))
+	c.JSONP(http.StatusOK, H{"id": 1})
+
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, MIMEJSON) {
+		t.Errorf("Content-Type=%q，期望 %s", got, MIMEJSON)
+	}
+	if strings.TrimSpace(w.Body.String()) != `{"id":1}` {
+		t.Errorf("响应为 %q", w.Body.String())
+	}
+}
+
+// failingRender 模拟模板执行出错
+type failingRender struct{}
+
+func (failingRender) Render(w io.Writer, name string, data interface{}) error {
+	io.WriteString(w, "<html>半截页面")
+	return errors.New(`template: user.html:3:10: executing "user.html" at <.Secret.Token>: can't evaluate field Token`)
+}
+
+func TestHTMLTemplateError(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/user", nil))
+	c.HTMLRender = failingRender{}
+	c.HTMLTemplate(http.StatusOK, "user.html", nil)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("状态码为 %d，期望 500", w.Code)
+	}
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Message != ErrInternalServer.Message {
+		t.Fatalf("应通过错误渲染器返回通用错误: %v %q", err, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "Token") || strings.Contains(w.Body.String(), "半截") {
+		t.Fatalf("模板错误和半截页面不应返回给客户端: %q", w.Body.String())
+	}
+	if err := c.LastError(); err == nil || !strings.Contains(err.Error(), "Token") {
+		t.Fatalf("原始错误应记录到 c.Errors，实际 %v", err)
+	}
+	if !c.IsAborted() {
+		t.Fatal("应中止后续处理")
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		format string
+		values []interface{}
+		want   string
+	}{
+		{"hello %s, %d", []interface{}{"gyweb", 2}, "hello gyweb, 2"},
+		// 没有参数时按原样输出，不做格式化
+		{"100% %s", nil, "100% %s"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c := NewContext(w, httptest.NewRequest("GET", "/", nil))
+		c.String(http.StatusCreated, tt.format, tt.values...)
+
+		if w.Code != http.StatusCreated || w.Body.String() != tt.want {
+			t.Errorf("String(%q) = %d %q，期望 201 %q", tt.format, w.Code, w.Body.String(), tt.want)
+		}
+		if got := w.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+			t.Errorf("Content-Type=%q", got)
+		}
+	}
+}
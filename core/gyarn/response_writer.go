@@ -0,0 +1,144 @@
+package gyarn
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter 包装 http.ResponseWriter，记录状态码、响应大小以及响应头是否已发送
+//
+// 调用 WriteHeader 只记录状态码，响应头在第一次写入响应体、Flush 或 WriteHeaderNow 时才真正发送，
+// 因此在此之前仍可以修改响应头和状态码；Before 注册的回调在响应头发送前执行
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+
+	// Status 返回响应状态码，未设置时为 200
+	Status() int
+	// Size 返回已写入的响应体字节数
+	Size() int
+	// Written 响应头是否已经发送
+	Written() bool
+	// WriteHeaderNow 立即发送响应头
+	WriteHeaderNow()
+	// Before 注册在响应头发送前执行的回调，后注册的先执行（与 defer 一致）
+	Before(fn func(ResponseWriter))
+	// Unwrap 返回被包装的 http.ResponseWriter，供 http.ResponseController 使用
+	Unwrap() http.ResponseWriter
+}
+
+// responseWriter ResponseWriter 的默认实现，内嵌在 Context 中随上下文池复用
+type responseWriter struct {
+	http.ResponseWriter
+	status   int
+	size     int
+	written  bool
+	hijacked bool
+	before   []func(ResponseWriter)
+}
+
+var _ ResponseWriter = (*responseWriter)(nil)
+
+// reset 绑定新的底层 ResponseWriter 并清空状态
+func (w *responseWriter) reset(rw http.ResponseWriter) {
+	w.ResponseWriter = rw
+	w.status = http.StatusOK
+	w.size = 0
+	w.written = false
+	w.hijacked = false
+	clear(w.before)
+	w.before = w.before[:0]
+}
+
+// WriteHeader 记录状态码，响应头发送后再调用会被忽略
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow 立即发送响应头
+func (w *responseWriter) WriteHeaderNow() {
+	if w.written || w.hijacked {
+		return
+	}
+	// 先取出回调，防止回调中写入响应时重复执行
+	before := w.before
+	w.before = nil
+	for i := len(before) - 1; i >= 0; i-- {
+		before[i](w)
+	}
+	w.before = before[:0]
+
+	w.written = true
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// Write 写入响应体，必要时先发送响应头
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+// WriteString 写入字符串，底层支持 io.StringWriter 时避免复制
+func (w *responseWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	var n int
+	var err error
+	if sw, ok := w.ResponseWriter.(interface{ WriteString(string) (int, error) }); ok {
+		n, err = sw.WriteString(s)
+	} else {
+		n, err = w.ResponseWriter.Write([]byte(s))
+	}
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Status() int { return w.status }
+
+func (w *responseWriter) Size() int { return w.size }
+
+func (w *responseWriter) Written() bool { return w.written }
+
+func (w *responseWriter) Before(fn func(ResponseWriter)) {
+	w.before = append(w.before, fn)
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Flush 发送响应头和已缓冲的数据，底层不支持时忽略
+func (w *responseWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError 与 Flush 相同，但返回底层的错误，http.ResponseController 优先使用该方法
+func (w *responseWriter) FlushError() error {
+	w.WriteHeaderNow()
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack 接管底层连接（如 WebSocket），接管后框架不再写入响应
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.written {
+		return nil, nil, errors.New("gyarn: 响应头已发送，无法接管连接")
+	}
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Push HTTP/2 服务端推送，底层不支持时返回 http.ErrNotSupported
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
@@ -0,0 +1,137 @@
+package gyarn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newTestWriter() (*httptest.ResponseRecorder, ResponseWriter) {
+	rec := httptest.NewRecorder()
+	c := NewContext(rec, httptest.NewRequest("GET", "/", nil))
+	return rec, c.Writer
+}
+
+func TestResponseWriterDeferredHeader(t *testing.T) {
+	rec, w := newTestWriter()
+	if w.Status() != http.StatusOK || w.Written() || w.Size() != 0 {
+		t.Fatalf("初始状态: status=%d written=%v size=%d", w.Status(), w.Written(), w.Size())
+	}
+
+	// 第一次写入前可以修改状态码和响应头，以最后一次为准
+	w.WriteHeader(http.StatusCreated)
+	w.Header().Set("X-Late", "1")
+	w.WriteHeader(http.StatusAccepted)
+	w.WriteHeader(0)
+	if w.Written() || rec.Flushed || w.Status() != http.StatusAccepted {
+		t.Fatalf("WriteHeader 不应立即发送: written=%v status=%d", w.Written(), w.Status())
+	}
+
+	w.Write([]byte("hello"))
+	w.(interface{ WriteString(string) (int, error) }).WriteString(", world")
+	// 发送后修改状态码无效
+	w.WriteHeader(http.StatusInternalServerError)
+	w.WriteHeaderNow()
+
+	if rec.Code != http.StatusAccepted || w.Status() != http.StatusAccepted {
+		t.Errorf("状态码 = %d/%d，期望 202", rec.Code, w.Status())
+	}
+	if rec.Result().Header.Get("X-Late") != "1" {
+		t.Error("发送前设置的响应头应被发送")
+	}
+	if !w.Written() || w.Size() != len("hello, world") || rec.Body.String() != "hello, world" {
+		t.Errorf("written=%v size=%d body=%q", w.Written(), w.Size(), rec.Body.String())
+	}
+}
+
+func TestResponseWriterBefore(t *testing.T) {
+	rec, w := newTestWriter()
+	var calls []string
+	w.Before(func(w ResponseWriter) {
+		calls = append(calls, "first")
+		// 回调中仍可以修改状态码和响应头
+		w.Header().Set("X-Session", "saved")
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	w.Before(func(w ResponseWriter) {
+		calls = append(calls, "second")
+		if w.Written() {
+			t.Error("回调执行时响应头不应已发送")
+		}
+	})
+
+	w.WriteHeaderNow()
+	w.Write([]byte("y"))
+	w.Flush()
+
+	if want := []string{"second", "first"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("回调执行顺序 %v，期望 %v 且只执行一次", calls, want)
+	}
+	if rec.Code != http.StatusUnauthorized || rec.Result().Header.Get("X-Session") != "saved" {
+		t.Errorf("回调中的修改应在发送前生效: %d %v", rec.Code, rec.Result().Header)
+	}
+	if rec.Body.String() != "y" || w.Size() != 1 {
+		t.Errorf("body=%q size=%d", rec.Body.String(), w.Size())
+	}
+
+	// 响应头发送后注册的回调不会执行
+	w.Before(func(ResponseWriter) { calls = append(calls, "late") })
+	w.Write([]byte("z"))
+	if len(calls) != 2 {
+		t.Errorf("发送后注册的回调不应执行: %v", calls)
+	}
+}
+
+func TestResponseWriterBeforeWrites(t *testing.T) {
+	rec, w := newTestWriter()
+	calls := 0
+	w.Before(func(w ResponseWriter) {
+		calls++
+		// 回调中写入响应不会重复执行回调
+		w.Write([]byte("x"))
+	})
+	w.Write([]byte("y"))
+	if calls != 1 || rec.Body.String() != "xy" || w.Size() != 2 {
+		t.Fatalf("calls=%d body=%q size=%d", calls, rec.Body.String(), w.Size())
+	}
+}
+
+func TestResponseWriterFlushAndHijack(t *testing.T) {
+	rec, w := newTestWriter()
+	w.WriteHeader(http.StatusAccepted)
+	w.Flush()
+	if !w.Written() || !rec.Flushed || rec.Code != http.StatusAccepted {
+		t.Fatalf("Flush 应发送响应头: written=%v flushed=%v code=%d", w.Written(), rec.Flushed, rec.Code)
+	}
+	if _, _, err := w.Hijack(); err == nil {
+		t.Fatal("响应头发送后接管连接应返回错误")
+	}
+	if err := w.Push("/app.js", nil); err != http.ErrNotSupported {
+		t.Errorf("底层不支持推送时应返回 http.ErrNotSupported，实际 %v", err)
+	}
+	if w.Unwrap() != rec {
+		t.Error("Unwrap 应返回底层 ResponseWriter")
+	}
+}
+
+func TestResponseWriterReset(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := NewContext(rec, httptest.NewRequest("GET", "/", nil))
+	called := false
+	c.Writer.Before(func(ResponseWriter) { called = true })
+	c.Status(http.StatusNotFound)
+	c.Writer.Write([]byte("old"))
+
+	// 上下文复用时清空上一次请求的状态和回调
+	called = false
+	rec2 := httptest.NewRecorder()
+	c.writermem.reset(rec2)
+	if c.Writer.Status() != http.StatusOK || c.Writer.Written() || c.Writer.Size() != 0 {
+		t.Fatalf("reset 后: status=%d written=%v size=%d", c.Writer.Status(), c.Writer.Written(), c.Writer.Size())
+	}
+	c.Writer.Write([]byte("new"))
+	if called || rec2.Code != http.StatusOK || rec2.Body.String() != "new" {
+		t.Fatalf("reset 后不应执行旧回调: called=%v code=%d body=%q", called, rec2.Code, rec2.Body.String())
+	}
+}
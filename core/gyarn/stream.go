@@ -0,0 +1,203 @@
+package gyarn
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MIMEEventStream Server-Sent Events 的内容类型
+const MIMEEventStream = "text/event-stream"
+
+// SSEEvent 一条 Server-Sent Events 消息
+type SSEEvent struct {
+	// ID 事件编号，客户端断线重连时通过 Last-Event-ID 请求头带回
+	ID string
+	// Event 事件名称，为空时客户端触发 message 事件
+	Event string
+	// Data 事件数据：string 和 []byte 原样输出，其他类型编码为 JSON
+	Data interface{}
+	// Retry 建议客户端断线后的重连间隔，为 0 时不输出
+	Retry time.Duration
+}
+
+// Flush 把已写入的数据立即发送给客户端，底层连接不支持时返回错误
+func (c *Context) Flush() error {
+	return http.NewResponseController(c.Writer).Flush()
+}
+
+// ClientGone 客户端是否已断开连接（或请求已被取消）
+func (c *Context) ClientGone() bool {
+	select {
+	case <-c.Request.Context().Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// Stream 流式输出响应，反复调用 step 直到其返回 false 或客户端断开，每次调用后自动刷新
+// 返回 true 表示客户端在输出结束前断开了连接
+//
+//	c.Stream(func(w io.Writer) bool {
+//		reading, ok := <-sensor
+//		if !ok {
+//			return false
+//		}
+//		fmt.Fprintln(w, reading)
+//		return true
+//	})
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	c.Writer.WriteHeaderNow()
+	for {
+		if c.ClientGone() {
+			return true
+		}
+		keepOpen := step(c.Writer)
+		if err := c.Flush(); err != nil {
+			return true
+		}
+		if !keepOpen {
+			return false
+		}
+	}
+}
+
+// LastEventID 返回客户端重连时携带的最后事件编号
+// 优先读取 Last-Event-ID 请求头，兼容部分 polyfill 使用的 lastEventId 查询参数
+func (c *Context) LastEventID() string {
+	if id := c.Request.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return c.Query("lastEventId")
+}
+
+// SSEvent 发送一条指定名称的 SSE 事件并立即刷新
+func (c *Context) SSEvent(name string, data interface{}) {
+	_ = c.WriteSSE(SSEEvent{Event: name, Data: data})
+}
+
+// WriteSSE 发送一条 SSE 事件并立即刷新，首次调用时写入 text/event-stream 相关响应头
+func (c *Context) WriteSSE(event SSEEvent) error {
+	c.startSSE()
+	var sb strings.Builder
+	if event.ID != "" {
+		sb.WriteString("id: ")
+		sb.WriteString(sseEscape(event.ID))
+		sb.WriteByte('\n')
+	}
+	if event.Event != "" {
+		sb.WriteString("event: ")
+		sb.WriteString(sseEscape(event.Event))
+		sb.WriteByte('\n')
+	}
+	if event.Retry > 0 {
+		sb.WriteString("retry: ")
+		sb.WriteString(strconv.FormatInt(event.Retry.Milliseconds(), 10))
+		sb.WriteByte('\n')
+	}
+	data, err := sseData(event.Data)
+	if err != nil {
+		return err
+	}
+	// 数据中的每一行都需要单独的 data: 前缀
+	for _, line := range strings.Split(data, "\n") {
+		sb.WriteString("data: ")
+		sb.WriteString(strings.TrimSuffix(line, "\r"))
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+	if _, err := io.WriteString(c.Writer, sb.String()); err != nil {
+		return err
+	}
+	return c.Flush()
+}
+
+// SSEHeartbeat 发送一条注释作为心跳，防止代理因连接空闲而断开
+func (c *Context) SSEHeartbeat() error {
+	c.startSSE()
+	if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+		return err
+	}
+	return c.Flush()
+}
+
+// StreamSSE 把 events 中的事件持续推送给客户端，直到 events 关闭或客户端断开
+// heartbeat 大于 0 时在空闲期间按该间隔发送心跳；返回 true 表示客户端先断开了连接
+//
+//	r.GET("/sensors", func(c *gyarn.Context) {
+//		events := hub.Subscribe(c.LastEventID())
+//		defer hub.Unsubscribe(events)
+//		c.StreamSSE(events, 15*time.Second)
+//	})
+func (c *Context) StreamSSE(events <-chan SSEEvent, heartbeat time.Duration) bool {
+	c.startSSE()
+	if err := c.Flush(); err != nil {
+		return true
+	}
+
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	done := c.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if err := c.WriteSSE(event); err != nil {
+				return true
+			}
+		case <-tick:
+			if err := c.SSEHeartbeat(); err != nil {
+				return true
+			}
+		}
+	}
+}
+
+// startSSE 写入 SSE 响应头和状态码，只在第一次调用时生效
+func (c *Context) startSSE() {
+	if c.Writer.Written() {
+		return
+	}
+	header := c.Writer.Header()
+	header.Set("Content-Type", MIMEEventStream+"; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 关闭 Nginx 的响应缓冲
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+}
+
+// sseData 把事件数据转换为字符串
+func sseData(data interface{}) (string, error) {
+	switch v := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+}
+
+// sseEscape 去掉字段中的换行，避免破坏事件格式
+func sseEscape(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
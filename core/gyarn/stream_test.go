@@ -0,0 +1,148 @@
+package gyarn
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteSSE(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/events", nil))
+	if err := c.WriteSSE(SSEEvent{ID: "7\n8", Event: "update", Data: "第一行\r\n第二行\n", Retry: 3 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	c.SSEvent("user", H{"id": 1})
+	if err := c.SSEHeartbeat(); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]string{
+		"Content-Type":      "text/event-stream; charset=utf-8",
+		"Cache-Control":     "no-cache",
+		"X-Accel-Buffering": "no",
+	} {
+		if got := w.Header().Get(key); got != want {
+			t.Errorf("%s = %q，期望 %q", key, got, want)
+		}
+	}
+	if !w.Flushed {
+		t.Error("每条事件后应刷新")
+	}
+	want := "id: 78\nevent: update\nretry: 3000\ndata: 第一行\ndata: 第二行\ndata: \n\n" +
+		"event: user\ndata: {\"id\":1}\n\n" +
+		": ping\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("响应为 %q，期望 %q", got, want)
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	req := httptest.NewRequest("GET", "/events?lastEventId=3", nil)
+	if got := NewContext(httptest.NewRecorder(), req).LastEventID(); got != "3" {
+		t.Errorf("查询参数 LastEventID = %q", got)
+	}
+	req.Header.Set("Last-Event-ID", "5")
+	if got := NewContext(httptest.NewRecorder(), req).LastEventID(); got != "5" {
+		t.Errorf("请求头优先，LastEventID = %q", got)
+	}
+}
+
+func TestStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/", nil))
+	n := 0
+	gone := c.Stream(func(w io.Writer) bool {
+		n++
+		io.WriteString(w, "x")
+		return n < 3
+	})
+	if gone || w.Body.String() != "xxx" || !w.Flushed {
+		t.Errorf("Stream 返回 %v，响应 %q", gone, w.Body.String())
+	}
+}
+
+func TestStreamClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	n := 0
+	gone := c.Stream(func(w io.Writer) bool {
+		n++
+		if n == 2 {
+			cancel()
+		}
+		return true
+	})
+	if !gone || n != 2 {
+		t.Errorf("客户端断开后应停止输出并返回 true，实际 %v，调用 %d 次", gone, n)
+	}
+}
+
+func TestStreamSSE(t *testing.T) {
+	events := make(chan SSEEvent, 2)
+	events <- SSEEvent{ID: "1", Data: "a"}
+	events <- SSEEvent{ID: "2", Data: "b"}
+	close(events)
+
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/", nil))
+	if gone := c.StreamSSE(events, 0); gone {
+		t.Error("events 关闭后应返回 false")
+	}
+	if got := w.Body.String(); got != "id: 1\ndata: a\n\nid: 2\ndata: b\n\n" {
+		t.Errorf("响应为 %q", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/event-stream; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestStreamSSEHeartbeat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		NewContext(w, req).StreamSSE(make(chan SSEEvent), 10*time.Millisecond)
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	// 没有事件时按间隔发送心跳注释
+	buf := make([]byte, len(": ping\n\n"))
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(buf), ": ping") {
+		t.Errorf("心跳为 %q", buf)
+	}
+}
+
+func TestStreamSSEClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	result := make(chan bool)
+	go func() {
+		result <- c.StreamSSE(make(chan SSEEvent), 0)
+	}()
+	cancel()
+
+	select {
+	case gone := <-result:
+		if !gone {
+			t.Error("客户端断开后应返回 true")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("客户端断开后 StreamSSE 没有返回")
+	}
+}
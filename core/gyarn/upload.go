@@ -0,0 +1,406 @@
+package gyarn
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/guyigood/gyweb/core/storage"
+)
+
+// DefaultMultipartMemory 解析 multipart 表单时保存在内存中的最大字节数，超出部分写入临时文件
+const DefaultMultipartMemory = 32 << 20
+
+// sniffLen 识别文件类型需要读取的字节数，与 http.DetectContentType 一致
+const sniffLen = 512
+
+// UploadConfig 请求体和上传文件的限制，通过 engine.SetUploadConfig 设置
+type UploadConfig struct {
+	// MaxBodySize 请求体最大字节数，超出时读取请求体返回 413 错误；0 表示不限制
+	MaxBodySize int64
+	// MaxMemory 解析 multipart 表单时保存在内存中的最大字节数，默认 32MB
+	MaxMemory int64
+	// MaxFileSize 单个文件最大字节数，0 表示不限制
+	MaxFileSize int64
+	// AllowedExts 允许的扩展名，如 ".jpg"，不区分大小写；为空表示不限制
+	AllowedExts []string
+	// AllowedTypes 允许的文件类型，按文件内容识别而不是客户端声明的类型，支持 "image/*"；为空表示不限制
+	AllowedTypes []string
+	// Storage SaveUploadedFile 使用的存储后端，为 nil 时保存到本地磁盘
+	Storage storage.Storage
+}
+
+// DefaultUploadConfig 返回默认配置：不限制大小和类型，保存到本地磁盘
+func DefaultUploadConfig() *UploadConfig {
+	return &UploadConfig{
+		MaxMemory: DefaultMultipartMemory,
+	}
+}
+
+// 上传相关错误，可直接交给 c.AbortWithError
+var (
+	ErrRequestTooLarge     = NewHTTPError(http.StatusRequestEntityTooLarge, "请求体过大")
+	ErrFileTooLarge        = NewHTTPError(http.StatusRequestEntityTooLarge, "文件过大")
+	ErrUnsupportedFileType = NewHTTPError(http.StatusUnsupportedMediaType, "不支持的文件类型")
+)
+
+// defaultStorage 未配置存储后端时使用的本地磁盘存储，路径相对于当前工作目录
+var defaultStorage = storage.NewLocal("")
+
+// uploadConfig 返回当前请求的上传配置
+func (c *Context) uploadConfig() *UploadConfig {
+	if c.Upload != nil {
+		return c.Upload
+	}
+	return defaultUploadConfig
+}
+
+var defaultUploadConfig = DefaultUploadConfig()
+
+// maxMemory 返回解析 multipart 使用的内存上限
+func (config *UploadConfig) maxMemory() int64 {
+	if config.MaxMemory > 0 {
+		return config.MaxMemory
+	}
+	return DefaultMultipartMemory
+}
+
+// storage 返回存储后端
+func (config *UploadConfig) storage() storage.Storage {
+	if config.Storage != nil {
+		return config.Storage
+	}
+	return defaultStorage
+}
+
+// check 检查文件名、大小和识别出的类型是否符合限制
+func (config *UploadConfig) check(filename string, size int64, contentType string) error {
+	if config.MaxFileSize > 0 && size > config.MaxFileSize {
+		return ErrFileTooLarge.WithDetails(H{"filename": filename, "max_size": config.MaxFileSize})
+	}
+	if len(config.AllowedExts) > 0 {
+		ext := strings.ToLower(filepath.Ext(filename))
+		allowed := false
+		for _, e := range config.AllowedExts {
+			if strings.EqualFold(e, ext) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return ErrUnsupportedFileType.WithDetails(H{"filename": filename, "ext": ext})
+		}
+	}
+	if len(config.AllowedTypes) > 0 && contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		allowed := false
+		for _, t := range config.AllowedTypes {
+			if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+				allowed = strings.HasPrefix(mediaType, prefix+"/")
+			} else {
+				allowed = strings.EqualFold(t, mediaType)
+			}
+			if allowed {
+				break
+			}
+		}
+		if !allowed {
+			return ErrUnsupportedFileType.WithDetails(H{"filename": filename, "type": mediaType})
+		}
+	}
+	return nil
+}
+
+// LimitRequestBody 按配置限制请求体大小，由引擎在处理请求前调用
+func (c *Context) LimitRequestBody() {
+	if max := c.uploadConfig().MaxBodySize; max > 0 && c.Request.Body != nil && c.Request.Body != http.NoBody {
+		// 传入最底层的 ResponseWriter，超出限制时 net/http 才会在响应后关闭连接，不再读取剩余的请求体
+		c.Request.Body = http.MaxBytesReader(unwrapWriter(c.Writer), c.Request.Body, max)
+	}
+}
+
+// unwrapWriter 逐层取出被包装的 http.ResponseWriter
+func unwrapWriter(w http.ResponseWriter) http.ResponseWriter {
+	for {
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return w
+		}
+		w = u.Unwrap()
+	}
+}
+
+// MultipartForm 解析并返回 multipart 表单，所有文件在读取时按配置检查大小、扩展名和类型
+//
+//	form, err := c.MultipartForm()
+//	for _, fh := range form.File["files"] {
+//		c.SaveUploadedFile(fh, "upload/"+fh.Filename)
+//	}
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if err := c.parseMultipartForm(); err != nil {
+		return nil, err
+	}
+	return c.Request.MultipartForm, nil
+}
+
+// FormFile 获取上传的文件，请求中的所有文件在读取时按配置检查大小、扩展名和类型
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if err := c.parseMultipartForm(); err != nil {
+		return nil, err
+	}
+	f, fh, err := c.Request.FormFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return fh, nil
+}
+
+// parseMultipartForm 解析 multipart 表单，请求体超出限制时返回 ErrRequestTooLarge
+// 配置了文件限制时边读取边检查，文件超出 MaxFileSize 或类型不符时立即停止读取，不会先把整个请求体写入内存或临时文件
+func (c *Context) parseMultipartForm() error {
+	if c.Request.MultipartForm != nil {
+		return nil
+	}
+	config := c.uploadConfig()
+	if !config.limitsFiles() {
+		return requestTooLarge(c.Request.ParseMultipartForm(config.maxMemory()))
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return err
+	}
+	form, err := readForm(config, reader)
+	if err != nil {
+		return err
+	}
+	// 与 http.Request.ParseMultipartForm 一致，把普通字段合并到 Form 和 PostForm
+	if c.Request.Form == nil {
+		c.Request.ParseForm()
+	}
+	if c.Request.PostForm == nil {
+		c.Request.PostForm = make(url.Values)
+	}
+	for k, v := range form.Value {
+		c.Request.Form[k] = append(c.Request.Form[k], v...)
+		c.Request.PostForm[k] = append(c.Request.PostForm[k], v...)
+	}
+	c.Request.MultipartForm = form
+	return nil
+}
+
+// limitsFiles 是否配置了单个文件的大小、扩展名或类型限制
+func (config *UploadConfig) limitsFiles() bool {
+	return config.MaxFileSize > 0 || len(config.AllowedExts) > 0 || len(config.AllowedTypes) > 0
+}
+
+// readForm 逐个读取并检查各部分，检查通过的内容重新编码后交给标准库解析为 multipart.Form
+func readForm(config *UploadConfig, reader *multipart.Reader) (*multipart.Form, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	done := make(chan error, 1)
+	go func() {
+		err := copyParts(config, reader, mw)
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+		done <- err
+	}()
+
+	form, err := multipart.NewReader(pr, mw.Boundary()).ReadForm(config.maxMemory())
+	// 解析提前失败时关闭管道，结束写入的 goroutine
+	pr.Close()
+	if copyErr := <-done; copyErr != nil && !errors.Is(copyErr, io.ErrClosedPipe) {
+		err = copyErr
+	}
+	if err != nil {
+		if form != nil {
+			form.RemoveAll()
+		}
+		return nil, err
+	}
+	return form, nil
+}
+
+// copyParts 把 reader 中的各部分检查后写入 mw
+func copyParts(config *UploadConfig, reader *multipart.Reader, mw *multipart.Writer) error {
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return requestTooLarge(err)
+		}
+		up, err := newUploadPart(config, part)
+		if err == nil {
+			var w io.Writer
+			if w, err = mw.CreatePart(part.Header); err == nil {
+				_, err = io.Copy(w, up)
+			}
+		}
+		// 出错时不调用 part.Close，它会读完该部分剩余的内容
+		if err != nil {
+			return requestTooLarge(err)
+		}
+		part.Close()
+	}
+}
+
+// requestTooLarge 把读取请求体超出 MaxBodySize 的错误转换为 ErrRequestTooLarge
+func requestTooLarge(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return ErrRequestTooLarge.WithError(err)
+	}
+	return err
+}
+
+// DetectFileType 根据文件内容识别类型，不信任客户端声明的 Content-Type
+func DetectFileType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// SaveUploadedFile 保存上传的文件，dst 为存储后端中的 key
+// 未配置存储后端时 dst 为本地路径，目录不存在时自动创建
+func (c *Context) SaveUploadedFile(header *multipart.FileHeader, dst string) error {
+	f, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// 按文件内容识别类型，不使用客户端声明的 Content-Type
+	contentType, err := DetectFileType(header)
+	if err != nil {
+		return err
+	}
+	return c.uploadConfig().storage().Save(c, dst, f, header.Size, contentType)
+}
+
+// UploadPart multipart 请求中的一个部分，Read 读取的是该部分的内容
+type UploadPart struct {
+	// FormName 表单字段名
+	FormName string
+	// FileName 文件名，普通字段为空
+	FileName string
+	// ContentType 文件按内容识别出的类型，普通字段为空
+	ContentType string
+	// Header 该部分的原始头信息
+	Header map[string][]string
+
+	reader io.Reader
+}
+
+// IsFile 是否为文件
+func (p *UploadPart) IsFile() bool {
+	return p.FileName != ""
+}
+
+func (p *UploadPart) Read(b []byte) (int, error) {
+	return p.reader.Read(b)
+}
+
+// StreamMultipart 逐个处理 multipart 请求中的各个部分，文件内容不会整体缓存在内存或临时文件中，
+// 适合大文件直接转存；文件部分在调用 fn 之前已按配置检查扩展名和类型，读取超出 MaxFileSize 时返回 ErrFileTooLarge
+//
+//	err := c.StreamMultipart(func(p *gyarn.UploadPart) error {
+//		if !p.IsFile() {
+//			return nil
+//		}
+//		return store.Save(c, "videos/"+p.FileName, p, -1, p.ContentType)
+//	})
+func (c *Context) StreamMultipart(fn func(part *UploadPart) error) error {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return err
+	}
+	config := c.uploadConfig()
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return requestTooLarge(err)
+		}
+
+		up, err := newUploadPart(config, part)
+		if err == nil {
+			err = fn(up)
+		}
+		if err != nil {
+			return err
+		}
+		part.Close()
+	}
+}
+
+// newUploadPart 包装 part，文件部分按内容识别类型、检查扩展名和类型，并限制读取长度不超过 MaxFileSize
+func newUploadPart(config *UploadConfig, part *multipart.Part) (*UploadPart, error) {
+	up := &UploadPart{
+		FormName: part.FormName(),
+		FileName: part.FileName(),
+		Header:   part.Header,
+		reader:   part,
+	}
+	if !up.IsFile() {
+		return up, nil
+	}
+	br := bufio.NewReaderSize(part, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	up.ContentType = http.DetectContentType(head)
+	if err := config.check(up.FileName, 0, up.ContentType); err != nil {
+		return nil, err
+	}
+	up.reader = br
+	if config.MaxFileSize > 0 {
+		up.reader = &limitedPart{r: br, remaining: config.MaxFileSize, filename: up.FileName, max: config.MaxFileSize}
+	}
+	return up, nil
+}
+
+// limitedPart 限制单个文件的读取长度，超出时返回 ErrFileTooLarge 而不是静默截断
+type limitedPart struct {
+	r         io.Reader
+	remaining int64
+	filename  string
+	max       int64
+}
+
+func (l *limitedPart) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrFileTooLarge.WithDetails(H{"filename": l.filename, "max_size": l.max})
+	}
+	// 多读一个字节，用于判断是否超出
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		n += int(l.remaining)
+		return n, ErrFileTooLarge.WithDetails(H{"filename": l.filename, "max_size": l.max})
+	}
+	return n, err
+}
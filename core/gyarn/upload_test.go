@@ -0,0 +1,252 @@
+package gyarn
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/storage"
+)
+
+// pngData 以 PNG 文件头开头的内容
+var pngData = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 100)...)
+
+// formFile multipart 请求中的一个文件
+type formFile struct {
+	field       string
+	name        string
+	contentType string
+	data        []byte
+}
+
+// countingReader 记录已读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += n
+	return n, err
+}
+
+// multipartRequest 构造包含普通字段和文件的 multipart 请求，返回的 countingReader 记录请求体被读取的字节数
+func multipartRequest(t *testing.T, fields map[string]string, files ...formFile) (*http.Request, *countingReader) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	for _, f := range files {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", `form-data; name="`+f.field+`"; filename="`+f.name+`"`)
+		header.Set("Content-Type", f.contentType)
+		w, err := mw.CreatePart(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(f.data)
+	}
+	mw.Close()
+	body := &countingReader{r: &buf}
+	req := httptest.NewRequest("POST", "/upload?from=query", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req, body
+}
+
+// uploadContext 创建使用指定上传配置的上下文
+func uploadContext(req *http.Request, config *UploadConfig) *Context {
+	c := NewContext(httptest.NewRecorder(), req)
+	c.Upload = config
+	c.LimitRequestBody()
+	return c
+}
+
+func TestFormFileLimits(t *testing.T) {
+	big := bytes.Repeat([]byte("a"), 1<<20)
+	tests := []struct {
+		name   string
+		config *UploadConfig
+		file   formFile
+		want   *HTTPError
+	}{
+		{"请求体过大", &UploadConfig{MaxBodySize: 1024}, formFile{"file", "a.txt", "text/plain", big}, ErrRequestTooLarge},
+		{"请求体过大且限制文件", &UploadConfig{MaxBodySize: 1024, MaxFileSize: 4096}, formFile{"file", "a.txt", "text/plain", big}, ErrRequestTooLarge},
+		{"文件过大", &UploadConfig{MaxFileSize: 1024}, formFile{"file", "a.txt", "text/plain", big}, ErrFileTooLarge},
+		{"扩展名不允许", &UploadConfig{AllowedExts: []string{".png"}}, formFile{"file", "a.txt", "text/plain", []byte("hi")}, ErrUnsupportedFileType},
+		// 按内容识别类型，不信任客户端声明的 Content-Type
+		{"类型不允许", &UploadConfig{AllowedTypes: []string{"image/png"}}, formFile{"file", "a.png", "image/png", []byte("hi")}, ErrUnsupportedFileType},
+		{"类型通配", &UploadConfig{AllowedTypes: []string{"image/*"}, AllowedExts: []string{".PNG"}}, formFile{"file", "a.png", "image/png", pngData}, nil},
+		{"大小正好", &UploadConfig{MaxFileSize: int64(len(pngData))}, formFile{"file", "a.png", "image/png", pngData}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, body := multipartRequest(t, nil, tt.file)
+			c := uploadContext(req, tt.config)
+			fh, err := c.FormFile("file")
+			if tt.want == nil {
+				if err != nil || fh.Size != int64(len(tt.file.data)) {
+					t.Fatalf("FormFile 失败: %v", err)
+				}
+				return
+			}
+			if he := ToHTTPError(err); err == nil || he.Code != tt.want.Code || he.Message != tt.want.Message {
+				t.Fatalf("错误为 %v，期望 %v", err, tt.want)
+			}
+			// 超出限制后立即停止读取请求体
+			if body.n > len(big)/2 {
+				t.Errorf("出错前读取了 %d 字节", body.n)
+			}
+		})
+	}
+}
+
+func TestMultipartFormValues(t *testing.T) {
+	req, _ := multipartRequest(t, map[string]string{"title": "相册"},
+		formFile{"files", "a.png", "image/png", pngData},
+		formFile{"files", "b.png", "image/png", pngData},
+	)
+	c := uploadContext(req, &UploadConfig{MaxFileSize: 1024, AllowedTypes: []string{"image/png"}})
+	form, err := c.MultipartForm()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer form.RemoveAll()
+
+	if len(form.File["files"]) != 2 || form.File["files"][1].Filename != "b.png" {
+		t.Fatalf("文件为 %v", form.File)
+	}
+	f, _ := form.File["files"][0].Open()
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if !bytes.Equal(data, pngData) {
+		t.Error("文件内容不一致")
+	}
+	if c.PostForm("title") != "相册" || c.Request.FormValue("from") != "query" {
+		t.Errorf("表单字段 title=%q from=%q", c.PostForm("title"), c.Request.FormValue("from"))
+	}
+}
+
+func TestSaveUploadedFileDetectsType(t *testing.T) {
+	store := storage.NewMemory()
+	req, _ := multipartRequest(t, nil, formFile{"file", "a.png", "image/png", []byte("not an image")})
+	c := uploadContext(req, &UploadConfig{Storage: store})
+	fh, err := c.FormFile("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SaveUploadedFile(fh, "upload/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	file, ok := store.Get("upload/a.png")
+	if !ok || string(file.Data) != "not an image" {
+		t.Fatalf("保存的文件为 %+v", file)
+	}
+	if file.ContentType != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type 为 %q，应按内容识别", file.ContentType)
+	}
+}
+
+func TestStreamMultipart(t *testing.T) {
+	req, _ := multipartRequest(t, map[string]string{"title": "视频"},
+		formFile{"file", "a.png", "image/png", pngData},
+	)
+	c := uploadContext(req, &UploadConfig{AllowedTypes: []string{"image/png"}})
+	var got []string
+	err := c.StreamMultipart(func(p *UploadPart) error {
+		data, err := io.ReadAll(p)
+		if err != nil {
+			return err
+		}
+		got = append(got, fmt.Sprintf("%s:%s:%s:%d", p.FormName, p.FileName, p.ContentType, len(data)))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"title:::6", fmt.Sprintf("file:a.png:image/png:%d", len(pngData))}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("各部分为 %v，期望 %v", got, want)
+	}
+}
+
+func TestStreamMultipartLimits(t *testing.T) {
+	big := bytes.Repeat([]byte("a"), 1<<20)
+	req, body := multipartRequest(t, nil, formFile{"file", "a.txt", "text/plain", big})
+	c := uploadContext(req, &UploadConfig{MaxFileSize: 1024})
+	err := c.StreamMultipart(func(p *UploadPart) error {
+		_, err := io.Copy(io.Discard, p)
+		return err
+	})
+	if err == nil || ToHTTPError(err).Message != ErrFileTooLarge.Message {
+		t.Fatalf("错误为 %v，期望文件过大", err)
+	}
+	if body.n > len(big)/2 {
+		t.Errorf("出错前读取了 %d 字节", body.n)
+	}
+
+	called := false
+	req, _ = multipartRequest(t, nil, formFile{"file", "a.exe", "application/octet-stream", []byte("MZ")})
+	c = uploadContext(req, &UploadConfig{AllowedExts: []string{".png"}})
+	err = c.StreamMultipart(func(p *UploadPart) error {
+		called = true
+		return nil
+	})
+	if called || err == nil || ToHTTPError(err).Code != http.StatusUnsupportedMediaType {
+		t.Errorf("扩展名不允许时不应调用 fn，错误为 %v", err)
+	}
+}
+
+func TestLimitedPart(t *testing.T) {
+	read := func(data string, max int64) (string, error) {
+		b, err := io.ReadAll(&limitedPart{r: strings.NewReader(data), remaining: max, max: max})
+		return string(b), err
+	}
+	if got, err := read("12345", 5); err != nil || got != "12345" {
+		t.Errorf("正好等于上限时应完整读取: %q %v", got, err)
+	}
+	got, err := read("123456", 5)
+	if err == nil || ToHTTPError(err).Message != ErrFileTooLarge.Message {
+		t.Errorf("超出上限时应返回文件过大: %v", err)
+	}
+	if got != "12345" {
+		t.Errorf("超出上限前读取的内容为 %q", got)
+	}
+}
+
+func TestRequestTooLargeClosesConnection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		c := NewContext(w, req)
+		c.Upload = &UploadConfig{MaxBodySize: 1024}
+		c.LimitRequestBody()
+		if _, err := c.FormFile("file"); err != nil {
+			c.AbortWithError(err)
+		}
+		c.Writer.WriteHeaderNow()
+	}))
+	defer server.Close()
+
+	req, _ := multipartRequest(t, nil, formFile{"file", "a.txt", "text/plain", bytes.Repeat([]byte("a"), 64<<10)})
+	req.RequestURI = ""
+	req.URL, _ = req.URL.Parse(server.URL + "/upload")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("状态码为 %d，期望 413", resp.StatusCode)
+	}
+	// 底层连接应在响应后关闭，不再读取剩余的请求体
+	if !resp.Close {
+		t.Error("超出请求体限制后应关闭连接")
+	}
+}
@@ -0,0 +1,201 @@
+// Package keyring 提供基于密钥环的签名和加密，用于 cookie、会话等需要防篡改或保密的短数据
+//
+// 密钥环中的第一个密钥用于生成新的签名和密文，其余密钥只用于校验和解密，
+// 轮换密钥时把新密钥放在最前面，旧密钥保留到使用它签发的数据全部过期后再移除
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalid 签名不匹配、密文无法解密或格式错误
+	ErrInvalid = errors.New("keyring: 签名或密文无效")
+	// ErrExpired 数据超过 MaxAge
+	ErrExpired = errors.New("keyring: 数据已过期")
+)
+
+// timestampLen 签发时间（Unix 秒）占用的字节数
+const timestampLen = 8
+
+var encoding = base64.RawURLEncoding
+
+// key 由一个密钥派生出的签名密钥和加密密钥
+type key struct {
+	sign []byte
+	enc  cipher.AEAD
+}
+
+// Ring 密钥环，可在多个 goroutine 中并发使用
+type Ring struct {
+	mu   sync.RWMutex
+	keys []key
+	// MaxAge 签名和密文的有效期，超过后 Verify、Decrypt 返回 ErrExpired；0 表示不限制
+	MaxAge time.Duration
+}
+
+// New 创建密钥环，第一个密钥为当前密钥，其余为轮换前的旧密钥
+//
+//	ring, err := keyring.New([]byte(os.Getenv("COOKIE_KEY")), []byte(os.Getenv("COOKIE_KEY_OLD")))
+func New(secrets ...[]byte) (*Ring, error) {
+	if len(secrets) == 0 {
+		return nil, errors.New("keyring: 至少需要一个密钥")
+	}
+	r := &Ring{}
+	for _, secret := range secrets {
+		k, err := deriveKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		r.keys = append(r.keys, k)
+	}
+	return r, nil
+}
+
+// Rotate 把 secret 设为当前密钥，keep 大于 0 时最多保留 keep 个密钥（含新密钥）
+func (r *Ring) Rotate(secret []byte, keep int) error {
+	k, err := deriveKey(secret)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := append([]key{k}, r.keys...)
+	if keep > 0 && len(keys) > keep {
+		keys = keys[:keep]
+	}
+	r.keys = keys
+	return nil
+}
+
+// deriveKey 从密钥派生出相互独立的签名密钥和 AES-256 加密密钥，密钥长度不限
+func deriveKey(secret []byte) (key, error) {
+	if len(secret) == 0 {
+		return key{}, errors.New("keyring: 密钥不能为空")
+	}
+	block, err := aes.NewCipher(derive(secret, "gyweb keyring encryption"))
+	if err != nil {
+		return key{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return key{}, err
+	}
+	return key{sign: derive(secret, "gyweb keyring signing"), enc: aead}, nil
+}
+
+func derive(secret []byte, label string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(label))
+	return h.Sum(nil)
+}
+
+// snapshot 返回当前的密钥列表
+func (r *Ring) snapshot() []key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.keys
+}
+
+// Sign 签名，name 参与签名（通常为 cookie 名），防止把一个 cookie 的值拿去冒充另一个
+// 返回值只防篡改，内容可以被读取，需要保密时使用 Encrypt
+func (r *Ring) Sign(name string, value []byte) string {
+	payload := encoding.EncodeToString(stamp(value))
+	return payload + "." + encoding.EncodeToString(mac(r.snapshot()[0].sign, name, payload))
+}
+
+// Verify 校验 Sign 生成的字符串并返回原始值，依次尝试密钥环中的所有密钥
+func (r *Ring) Verify(name, signed string) ([]byte, error) {
+	payload, sig, ok := cutLast(signed, ".")
+	if !ok {
+		return nil, ErrInvalid
+	}
+	expected, err := encoding.DecodeString(sig)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	for _, k := range r.snapshot() {
+		if hmac.Equal(expected, mac(k.sign, name, payload)) {
+			data, err := encoding.DecodeString(payload)
+			if err != nil {
+				return nil, ErrInvalid
+			}
+			return r.unstamp(data)
+		}
+	}
+	return nil, ErrInvalid
+}
+
+// Encrypt 使用 AES-256-GCM 加密，name 作为附加数据参与认证
+func (r *Ring) Encrypt(name string, value []byte) (string, error) {
+	aead := r.snapshot()[0].enc
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+timestampLen+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(aead.Seal(nonce, nonce, stamp(value), []byte(name))), nil
+}
+
+// Decrypt 解密 Encrypt 生成的字符串，依次尝试密钥环中的所有密钥
+func (r *Ring) Decrypt(name, encrypted string) ([]byte, error) {
+	data, err := encoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	for _, k := range r.snapshot() {
+		size := k.enc.NonceSize()
+		if len(data) < size {
+			return nil, ErrInvalid
+		}
+		if plain, err := k.enc.Open(nil, data[:size], data[size:], []byte(name)); err == nil {
+			return r.unstamp(plain)
+		}
+	}
+	return nil, ErrInvalid
+}
+
+func mac(signKey []byte, name, payload string) []byte {
+	h := hmac.New(sha256.New, signKey)
+	h.Write([]byte(name))
+	h.Write([]byte{'|'})
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// stamp 在值前加上签发时间
+func stamp(value []byte) []byte {
+	out := make([]byte, timestampLen, timestampLen+len(value))
+	binary.BigEndian.PutUint64(out, uint64(time.Now().Unix()))
+	return append(out, value...)
+}
+
+// unstamp 去掉签发时间并检查有效期
+func (r *Ring) unstamp(data []byte) ([]byte, error) {
+	if len(data) < timestampLen {
+		return nil, ErrInvalid
+	}
+	if r.MaxAge > 0 {
+		issued := time.Unix(int64(binary.BigEndian.Uint64(data)), 0)
+		if time.Since(issued) > r.MaxAge {
+			return nil, ErrExpired
+		}
+	}
+	return data[timestampLen:], nil
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
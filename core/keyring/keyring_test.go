@@ -0,0 +1,97 @@
+package keyring
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	ring, err := New([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := ring.Sign("uid", []byte("42"))
+	got, err := ring.Verify("uid", signed)
+	if err != nil || string(got) != "42" {
+		t.Fatalf("Verify() = %q, %v", got, err)
+	}
+	if _, err := ring.Verify("other", signed); !errors.Is(err, ErrInvalid) {
+		t.Errorf("不同名称应校验失败，实际 %v", err)
+	}
+	// 用另一个值的内容搭配原签名
+	payload, _, _ := strings.Cut(ring.Sign("uid", []byte("1")), ".")
+	_, sig, _ := strings.Cut(signed, ".")
+	tampered := payload + "." + sig
+	if _, err := ring.Verify("uid", tampered); !errors.Is(err, ErrInvalid) {
+		t.Errorf("篡改后应校验失败，实际 %v", err)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	ring, _ := New([]byte("secret"))
+	encrypted, err := ring.Encrypt("token", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(encrypted, "hello") {
+		t.Fatal("密文中不应包含明文")
+	}
+	got, err := ring.Decrypt("token", encrypted)
+	if err != nil || string(got) != "hello" {
+		t.Fatalf("Decrypt() = %q, %v", got, err)
+	}
+	if _, err := ring.Decrypt("other", encrypted); !errors.Is(err, ErrInvalid) {
+		t.Errorf("不同名称应解密失败，实际 %v", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	ring, _ := New([]byte("old"))
+	signed := ring.Sign("a", []byte("v"))
+	encrypted, _ := ring.Encrypt("a", []byte("v"))
+
+	if err := ring.Rotate([]byte("new"), 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ring.Verify("a", signed); err != nil {
+		t.Errorf("轮换后旧签名应仍然有效: %v", err)
+	}
+	if _, err := ring.Decrypt("a", encrypted); err != nil {
+		t.Errorf("轮换后旧密文应仍然可以解密: %v", err)
+	}
+
+	fresh, _ := New([]byte("new"))
+	if _, err := fresh.Verify("a", ring.Sign("a", []byte("v"))); err != nil {
+		t.Errorf("轮换后应使用新密钥签名: %v", err)
+	}
+
+	ring.Rotate([]byte("newer"), 2)
+	if _, err := ring.Verify("a", signed); !errors.Is(err, ErrInvalid) {
+		t.Errorf("移除的密钥签发的数据应校验失败，实际 %v", err)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	ring, _ := New([]byte("secret"))
+	ring.MaxAge = time.Hour
+	data := stamp([]byte("v"))
+	// 把签发时间改为两小时前
+	old := time.Now().Add(-2 * time.Hour).Unix()
+	for i := 0; i < timestampLen; i++ {
+		data[i] = byte(old >> (8 * (timestampLen - 1 - i)))
+	}
+	if _, err := ring.unstamp(data); !errors.Is(err, ErrExpired) {
+		t.Errorf("超过 MaxAge 应返回 ErrExpired，实际 %v", err)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := New(); err == nil {
+		t.Error("没有密钥时应返回错误")
+	}
+	if _, err := New([]byte{}); err == nil {
+		t.Error("空密钥应返回错误")
+	}
+}
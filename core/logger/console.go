@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// consoleHandler 控制台格式的 slog.Handler
+// 输出形如：2006-01-02 15:04:05 [DEBUG] engine.go:120 收到请求 method=GET path=/api
+type consoleHandler struct {
+	options *slog.HandlerOptions
+	out     io.Writer
+	mu      *sync.Mutex
+	attrs   []byte // 通过 WithAttrs 预先格式化的字段
+	group   string // 通过 WithGroup 设置的字段前缀
+}
+
+// newConsoleHandler 创建控制台格式的处理器
+func newConsoleHandler(out io.Writer, options *slog.HandlerOptions) *consoleHandler {
+	if options == nil {
+		options = &slog.HandlerOptions{}
+	}
+	return &consoleHandler{options: options, out: out, mu: &sync.Mutex{}}
+}
+
+// Enabled 判断级别是否输出
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.options.Level != nil {
+		minLevel = h.options.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle 格式化并输出一条日志
+func (h *consoleHandler) Handle(_ context.Context, record slog.Record) error {
+	buf := bytes.NewBuffer(make([]byte, 0, 256))
+	if !record.Time.IsZero() {
+		buf.WriteString(record.Time.Format(time.DateTime))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString("[" + record.Level.String() + "] ")
+	if h.options.AddSource && record.PC != 0 {
+		frames := runtime.CallersFrames([]uintptr{record.PC})
+		frame, _ := frames.Next()
+		buf.WriteString(filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line) + " ")
+	}
+	buf.WriteString(record.Message)
+	buf.Write(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		appendAttr(buf, h.group, attr)
+		return true
+	})
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.out.Write(buf.Bytes())
+	return err
+}
+
+// WithAttrs 返回附带固定字段的处理器
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	buf := bytes.NewBuffer(append([]byte(nil), h.attrs...))
+	for _, attr := range attrs {
+		appendAttr(buf, h.group, attr)
+	}
+	clone := *h
+	clone.attrs = buf.Bytes()
+	return &clone
+}
+
+// WithGroup 返回字段名带分组前缀的处理器
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group = h.group + name + "."
+	return &clone
+}
+
+// appendAttr 以 key=value 形式追加字段，值包含空白时加引号
+func appendAttr(buf *bytes.Buffer, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, child := range attr.Value.Group() {
+			appendAttr(buf, prefix+attr.Key+".", child)
+		}
+		return
+	}
+	value := attr.Value.String()
+	if attr.Value.Kind() == slog.KindTime {
+		value = attr.Value.Time().Format(time.DateTime)
+	}
+	if needsQuote(value) {
+		value = strconv.Quote(value)
+	}
+	fmt.Fprintf(buf, " %s%s=%s", prefix, attr.Key, value)
+}
+
+// needsQuote 判断值是否需要加引号
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r == ' ' || r == '=' || r == '"' || r < 0x20 {
+			return true
+		}
+	}
+	return false
+}
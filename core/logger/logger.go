@@ -0,0 +1,208 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Level 日志级别
+type Level = slog.Level
+
+// 日志级别
+const (
+	LevelDebug = slog.LevelDebug
+	LevelInfo  = slog.LevelInfo
+	LevelWarn  = slog.LevelWarn
+	LevelError = slog.LevelError
+)
+
+// 日志输出格式
+const (
+	FormatConsole = "console" // 便于阅读的单行文本
+	FormatJSON    = "json"    // 每行一个 JSON 对象，便于日志系统采集
+)
+
+// Logger 框架日志接口
+// args 为成对出现的键值，与 log/slog 相同，例如 Info("请求完成", "status", 200, "path", "/api")
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+	// With 返回附带固定字段的子日志器
+	With(args ...any) Logger
+	// Enabled 判断指定级别是否会被输出，可用于跳过代价较高的字段计算
+	Enabled(level Level) bool
+}
+
+// Config 日志配置
+type Config struct {
+	// Level 最低输出级别，默认 Info
+	Level Level
+	// Format 输出格式：console 或 json，默认 console
+	Format string
+	// Output 输出目标，默认 os.Stdout
+	Output io.Writer
+	// AddSource 是否输出调用位置（文件:行号）
+	AddSource bool
+}
+
+// slogLogger 基于 log/slog 的 Logger 实现
+type slogLogger struct {
+	handler slog.Handler
+	level   *slog.LevelVar
+}
+
+// New 根据配置创建日志器
+func New(config Config) Logger {
+	if config.Output == nil {
+		config.Output = os.Stdout
+	}
+	level := new(slog.LevelVar)
+	level.Set(config.Level)
+
+	options := &slog.HandlerOptions{
+		Level:     level,
+		AddSource: config.AddSource,
+	}
+	var handler slog.Handler
+	if strings.EqualFold(config.Format, FormatJSON) {
+		handler = slog.NewJSONHandler(config.Output, options)
+	} else {
+		handler = newConsoleHandler(config.Output, options)
+	}
+	return &slogLogger{handler: handler, level: level}
+}
+
+// NewSlog 使用已有的 slog.Handler 创建日志器，便于接入第三方日志系统
+func NewSlog(handler slog.Handler) Logger {
+	return &slogLogger{handler: handler}
+}
+
+func (l *slogLogger) Debug(msg string, args ...any) { l.log(1, LevelDebug, msg, args...) }
+func (l *slogLogger) Info(msg string, args ...any)  { l.log(1, LevelInfo, msg, args...) }
+func (l *slogLogger) Warn(msg string, args ...any)  { l.log(1, LevelWarn, msg, args...) }
+func (l *slogLogger) Error(msg string, args ...any) { l.log(1, LevelError, msg, args...) }
+
+// With 返回附带固定字段的子日志器
+func (l *slogLogger) With(args ...any) Logger {
+	if len(args) == 0 {
+		return l
+	}
+	return &slogLogger{handler: slog.New(l.handler).With(args...).Handler(), level: l.level}
+}
+
+// Enabled 判断指定级别是否会被输出
+func (l *slogLogger) Enabled(level Level) bool {
+	return l.handler.Enabled(context.Background(), level)
+}
+
+// SetLevel 调整日志级别
+func (l *slogLogger) SetLevel(level Level) {
+	if l.level != nil {
+		l.level.Set(level)
+	}
+}
+
+// log 输出日志，depth 为相对调用者需要额外跳过的栈帧数
+func (l *slogLogger) log(depth int, level Level, msg string, args ...any) {
+	ctx := context.Background()
+	if !l.handler.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	// 跳过 runtime.Callers、log 以及调用 log 的方法
+	runtime.Callers(depth+2, pcs[:])
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	record.Add(args...)
+	_ = l.handler.Handle(ctx, record)
+}
+
+// defaultLogger 框架默认日志器
+var defaultLogger atomic.Value
+
+func init() {
+	SetDefault(New(Config{Level: LevelInfo, Format: FormatConsole}))
+}
+
+// loggerHolder 包装 Logger，保证 atomic.Value 中存储的类型一致
+type loggerHolder struct {
+	Logger
+}
+
+// SetDefault 设置框架默认日志器，engine、middleware、orm 等均通过它输出
+func SetDefault(l Logger) {
+	if l == nil {
+		return
+	}
+	defaultLogger.Store(loggerHolder{l})
+}
+
+// Default 返回框架默认日志器
+func Default() Logger {
+	return defaultLogger.Load().(loggerHolder).Logger
+}
+
+// SetLevel 调整默认日志器的级别
+// 仅对 New 创建的日志器生效，自定义实现需自行控制级别
+func SetLevel(level Level) {
+	if l, ok := Default().(interface{ SetLevel(Level) }); ok {
+		l.SetLevel(level)
+	}
+}
+
+// GetLevel 返回默认日志器的级别，ok 为 false 表示默认日志器不是 New 创建的，无法获取级别
+func GetLevel() (level Level, ok bool) {
+	if l, ok := Default().(*slogLogger); ok && l.level != nil {
+		return l.level.Level(), true
+	}
+	return LevelInfo, false
+}
+
+// Enabled 判断默认日志器是否会输出指定级别
+func Enabled(level Level) bool {
+	return Default().Enabled(level)
+}
+
+// Debug 使用默认日志器输出调试日志
+func Debug(msg string, args ...any) { LogDepth(1, LevelDebug, msg, args...) }
+
+// Info 使用默认日志器输出信息日志
+func Info(msg string, args ...any) { LogDepth(1, LevelInfo, msg, args...) }
+
+// Warn 使用默认日志器输出警告日志
+func Warn(msg string, args ...any) { LogDepth(1, LevelWarn, msg, args...) }
+
+// Error 使用默认日志器输出错误日志
+func Error(msg string, args ...any) { LogDepth(1, LevelError, msg, args...) }
+
+// With 返回附带固定字段的默认日志器
+func With(args ...any) Logger {
+	return Default().With(args...)
+}
+
+// LogDepth 使用默认日志器输出日志，depth 为调用位置需要额外向上跳过的栈帧数
+// 供封装日志的辅助函数使用，使 AddSource 输出的是真正的调用位置
+func LogDepth(depth int, level Level, msg string, args ...any) {
+	l := Default()
+	if sl, ok := l.(*slogLogger); ok {
+		sl.log(depth+1, level, msg, args...)
+		return
+	}
+	switch {
+	case level >= LevelError:
+		l.Error(msg, args...)
+	case level >= LevelWarn:
+		l.Warn(msg, args...)
+	case level >= LevelInfo:
+		l.Info(msg, args...)
+	default:
+		l.Debug(msg, args...)
+	}
+}
@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(Config{Level: LevelWarn, Output: &buf})
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+	out := buf.String()
+	if strings.Contains(out, "debug") || strings.Contains(out, "info") {
+		t.Errorf("低于 Warn 的日志不应输出: %q", out)
+	}
+	if !strings.Contains(out, "[WARN] warn") || !strings.Contains(out, "[ERROR] error") {
+		t.Errorf("Warn 及以上应输出: %q", out)
+	}
+	if l.Enabled(LevelInfo) || !l.Enabled(LevelWarn) {
+		t.Error("Enabled 与级别不一致")
+	}
+
+	// 子日志器共享级别
+	child := l.With("module", "orm")
+	l.(interface{ SetLevel(Level) }).SetLevel(LevelDebug)
+	if !child.Enabled(LevelDebug) {
+		t.Error("调整级别后子日志器应同步生效")
+	}
+}
+
+func TestConsoleFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(Config{Level: LevelDebug, Output: &buf}).With("module", "engine")
+	l.Info("请求完成", "status", 200, "path", "/api users", "empty", "")
+
+	line := strings.TrimSuffix(buf.String(), "\n")
+	// 2006-01-02 15:04:05 [INFO] 请求完成 module=engine status=200 ...
+	if len(line) < 20 || line[4] != '-' || line[19] != ' ' {
+		t.Fatalf("应以时间开头: %q", line)
+	}
+	if want := `[INFO] 请求完成 module=engine status=200 path="/api users" empty=""`; line[20:] != want {
+		t.Errorf("日志行 %q，期望 %q", line[20:], want)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(Config{Level: LevelInfo, Format: FormatJSON, Output: &buf}).With("module", "orm")
+	l.Warn("慢查询", "sql", `SELECT * FROM "user"`, "ms", 1500)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("应输出 JSON: %v %q", err, buf.String())
+	}
+	want := map[string]interface{}{"level": "WARN", "msg": "慢查询", "module": "orm", "sql": `SELECT * FROM "user"`, "ms": float64(1500)}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v，期望 %v", k, entry[k], v)
+		}
+	}
+	if _, ok := entry["time"]; !ok {
+		t.Error("缺少 time 字段")
+	}
+}
+
+// logHelper 模拟封装日志的辅助函数
+func logHelper(msg string) {
+	LogDepth(1, LevelInfo, msg)
+}
+
+func TestLogDepthSource(t *testing.T) {
+	prev := Default()
+	defer SetDefault(prev)
+	var buf bytes.Buffer
+	SetDefault(New(Config{Level: LevelInfo, Output: &buf, AddSource: true}))
+
+	_, _, line, _ := runtime.Caller(0)
+	logHelper("from helper") // 调用位置为这一行
+	Info("direct")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("输出 %q", buf.String())
+	}
+	if want := "logger_test.go:" + strconv.Itoa(line+1) + " from helper"; !strings.Contains(lines[0], want) {
+		t.Errorf("LogDepth 应记录辅助函数的调用位置: %q，期望包含 %q", lines[0], want)
+	}
+	if want := "logger_test.go:" + strconv.Itoa(line+2) + " direct"; !strings.Contains(lines[1], want) {
+		t.Errorf("包级函数应记录调用位置: %q，期望包含 %q", lines[1], want)
+	}
+}
+
+func TestDefaultLevel(t *testing.T) {
+	prev := Default()
+	defer SetDefault(prev)
+	SetDefault(New(Config{Level: LevelWarn}))
+
+	if level, ok := GetLevel(); !ok || level != LevelWarn {
+		t.Fatalf("GetLevel = %v %v", level, ok)
+	}
+	SetLevel(LevelDebug)
+	if !Enabled(LevelDebug) {
+		t.Fatal("SetLevel 应调整默认日志器")
+	}
+	SetDefault(NewSlog(New(Config{}).(*slogLogger).handler))
+	if _, ok := GetLevel(); ok {
+		t.Error("NewSlog 创建的日志器无法获取级别")
+	}
+}
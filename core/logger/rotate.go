@@ -0,0 +1,221 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat 备份文件名中的时间格式
+const backupTimeFormat = "20060102-150405.000"
+
+// RotateConfig 滚动日志文件配置
+type RotateConfig struct {
+	// Filename 日志文件路径，目录不存在时自动创建
+	Filename string
+	// MaxSize 单个文件的最大字节数，超过后滚动；0 表示不按大小滚动
+	MaxSize int64
+	// MaxAge 备份文件的保留时间，0 表示不按时间删除
+	MaxAge time.Duration
+	// MaxBackups 最多保留的备份文件数，0 表示不限制
+	MaxBackups int
+	// LocalTime 备份文件名使用本地时间，默认使用 UTC
+	LocalTime bool
+}
+
+// RotatingFile 按大小滚动的日志文件，可在多个 goroutine 中并发写入
+// 滚动时当前文件重命名为 name-20060102-150405.000.ext（同一毫秒内重复滚动时追加 -1、-2 等序号），
+// 并按 MaxAge、MaxBackups 删除旧的备份
+//
+//	file, err := logger.NewRotatingFile(logger.RotateConfig{
+//		Filename:   "logs/access.log",
+//		MaxSize:    100 << 20,
+//		MaxAge:     7 * 24 * time.Hour,
+//		MaxBackups: 10,
+//	})
+type RotatingFile struct {
+	config RotateConfig
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+}
+
+// NewRotatingFile 打开滚动日志文件，已存在时追加写入
+func NewRotatingFile(config RotateConfig) (*RotatingFile, error) {
+	if config.Filename == "" {
+		return nil, errors.New("logger: 日志文件路径不能为空")
+	}
+	r := &RotatingFile{config: config}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write 写入日志，写入后超过 MaxSize 之前先滚动
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.config.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.config.MaxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate 立即滚动，可用于按天滚动等自定义策略
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rotate()
+}
+
+// Close 关闭文件
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// open 打开日志文件
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.config.Filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.config.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// rotate 把当前文件重命名为备份并打开新文件，调用方需持有锁
+// 关闭或重命名失败时重新打开原文件，之后的日志继续写入原文件
+func (r *RotatingFile) rotate() error {
+	if r.file != nil {
+		err := r.file.Close()
+		r.file = nil
+		if err != nil {
+			return r.reopen(err)
+		}
+	}
+	if err := os.Rename(r.config.Filename, r.backupName()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return r.reopen(err)
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	r.cleanup()
+	return nil
+}
+
+// reopen 滚动失败后重新打开原文件，返回滚动失败的原因
+func (r *RotatingFile) reopen(err error) error {
+	if openErr := r.open(); openErr != nil {
+		return errors.Join(err, openErr)
+	}
+	return err
+}
+
+// backupName 返回尚不存在的备份文件名，避免同一毫秒内滚动多次时覆盖之前的备份
+func (r *RotatingFile) backupName() string {
+	now := time.Now()
+	if !r.config.LocalTime {
+		now = now.UTC()
+	}
+	ext := filepath.Ext(r.config.Filename)
+	prefix := strings.TrimSuffix(r.config.Filename, ext) + "-" + now.Format(backupTimeFormat)
+	backup := prefix + ext
+	for seq := 1; ; seq++ {
+		// 不存在（或无法访问，由 Rename 返回错误）时使用该名称
+		if _, err := os.Lstat(backup); err != nil {
+			return backup
+		}
+		backup = prefix + "-" + strconv.Itoa(seq) + ext
+	}
+}
+
+// cleanup 删除超出 MaxAge、MaxBackups 的备份文件
+func (r *RotatingFile) cleanup() {
+	if r.config.MaxAge <= 0 && r.config.MaxBackups <= 0 {
+		return
+	}
+	ext := filepath.Ext(r.config.Filename)
+	prefix := filepath.Base(strings.TrimSuffix(r.config.Filename, ext)) + "-"
+	dir := filepath.Dir(r.config.Filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path string
+		t    time.Time
+		seq  int
+	}
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		seq := 0
+		if len(ts) > len(backupTimeFormat) {
+			n, err := strconv.Atoi(strings.TrimPrefix(ts[len(backupTimeFormat):], "-"))
+			if err != nil || ts[len(backupTimeFormat)] != '-' {
+				continue
+			}
+			ts, seq = ts[:len(backupTimeFormat)], n
+		}
+		loc := time.UTC
+		if r.config.LocalTime {
+			loc = time.Local
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, ts, loc)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, name), t: t, seq: seq})
+	}
+	// 新的在前，时间相同时序号大的更新
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].t.Equal(backups[j].t) {
+			return backups[i].t.After(backups[j].t)
+		}
+		return backups[i].seq > backups[j].seq
+	})
+
+	cutoff := time.Now().Add(-r.config.MaxAge)
+	for i, b := range backups {
+		if (r.config.MaxBackups > 0 && i >= r.config.MaxBackups) || (r.config.MaxAge > 0 && b.t.Before(cutoff)) {
+			if err := os.Remove(b.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				fmt.Fprintf(os.Stderr, "logger: 删除旧日志文件失败: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "logs", "app.log")
+	f, err := NewRotatingFile(RotateConfig{Filename: name, MaxSize: 10, MaxBackups: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "third\n" {
+		t.Fatalf("当前文件内容 = %q", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(name))
+	var backups []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), "app-") {
+			backups = append(backups, e.Name())
+		}
+	}
+	if len(backups) != 1 {
+		t.Fatalf("应只保留 1 个备份，实际 %v", backups)
+	}
+	// 保留的是最新的备份
+	if data, _ := os.ReadFile(filepath.Join(filepath.Dir(name), backups[0])); string(data) != "second\n" {
+		t.Fatalf("保留的备份内容 = %q", data)
+	}
+}
+
+func TestRotatingFileUniqueBackups(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	f, err := NewRotatingFile(RotateConfig{Filename: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// 同一毫秒内多次滚动不能覆盖之前的备份
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(f, "line %d\n", i)
+		if err := f.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var lines []string
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), "app-") {
+			data, _ := os.ReadFile(filepath.Join(dir, e.Name()))
+			lines = append(lines, string(data))
+		}
+	}
+	sort.Strings(lines)
+	if got := strings.Join(lines, ""); got != "line 0\nline 1\nline 2\nline 3\nline 4\n" {
+		t.Fatalf("备份内容 = %q", got)
+	}
+}
+
+func TestRotatingFileCleanupOrder(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	ts := time.Now().UTC().Format(backupTimeFormat)
+	for _, backup := range []string{"app-" + ts + ".log", "app-" + ts + "-1.log", "app-" + ts + "-2.log"} {
+		os.WriteFile(filepath.Join(dir, backup), nil, 0644)
+	}
+	f, err := NewRotatingFile(RotateConfig{Filename: name, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.cleanup()
+
+	// 时间相同时序号大的更新，最早的无序号备份被删除
+	if _, err := os.Stat(filepath.Join(dir, "app-"+ts+".log")); !os.IsNotExist(err) {
+		t.Error("最旧的备份应被删除")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app-"+ts+"-2.log")); err != nil {
+		t.Error("最新的备份应保留")
+	}
+}
+
+func TestRotateFailureReopens(t *testing.T) {
+	dir := t.TempDir()
+	// 备份文件名比原文件名多出时间戳，超出文件名长度限制后重命名失败
+	name := filepath.Join(dir, strings.Repeat("a", 240)+".log")
+	f, err := NewRotatingFile(RotateConfig{Filename: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("before\n"))
+	if err := f.Rotate(); err == nil {
+		t.Fatal("重命名失败时应返回错误")
+	}
+	if _, err := f.Write([]byte("after\n")); err != nil {
+		t.Fatalf("滚动失败后应继续写入原文件: %v", err)
+	}
+	if data, _ := os.ReadFile(name); string(data) != "before\nafter\n" {
+		t.Fatalf("原文件内容 = %q", data)
+	}
+}
@@ -9,8 +9,8 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/gorilla/sessions"
 	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/keyring"
 )
 
 // AuthConfig 认证中间件配置
@@ -57,18 +57,33 @@ type JWTClaims struct {
 
 // SessionConfig Session认证配置
 type SessionConfig struct {
-	SecretKey string // Session密钥
-	MaxAge    int    // Session过期时间（秒）
-	Path      string // Cookie路径
-	Domain    string // Cookie域名
-	Secure    bool   // 是否只在HTTPS下传输
-	HttpOnly  bool   // 是否禁止JavaScript访问
+	SecretKey string        // Session密钥，为空时使用随机密钥（重启后会话失效）
+	MaxAge    int           // Session过期时间（秒）
+	Path      string        // Cookie路径
+	Domain    string        // Cookie域名
+	Secure    bool          // 是否只在HTTPS下传输
+	HttpOnly  bool          // 是否禁止JavaScript访问
+	SameSite  http.SameSite // 跨站请求时是否发送Cookie
 }
 
-// SessionStore Session存储
-var sessionStore *sessions.CookieStore
+// options 转换为会话属性
+func (config *SessionConfig) options() *SessionOptions {
+	path := config.Path
+	if path == "" {
+		path = "/"
+	}
+	return &SessionOptions{
+		Path:     path,
+		Domain:   config.Domain,
+		MaxAge:   config.MaxAge,
+		Secure:   config.Secure,
+		HttpOnly: config.HttpOnly,
+		SameSite: config.SameSite,
+	}
+}
 
-// InitSessionStore 初始化Session存储
+// InitSessionStore 初始化默认Session存储，使用加密的cookie保存会话
+// 需要服务端存储时使用 SetSessionStore(NewRedisSessionStore(...))
 func InitSessionStore(config *SessionConfig) {
 	// 生成随机密钥
 	key := make([]byte, 32)
@@ -78,7 +93,11 @@ func InitSessionStore(config *SessionConfig) {
 	if config.SecretKey != "" {
 		key = []byte(config.SecretKey)
 	}
-	sessionStore = sessions.NewCookieStore(key)
+	ring, err := keyring.New(key)
+	if err != nil {
+		panic(err)
+	}
+	SetSessionStore(NewCookieSessionStore(ring, config.options()))
 }
 
 // UseJWT 使用JWT认证
@@ -162,16 +181,13 @@ func (m *AuthManager) UseSession(config *SessionConfig) *AuthManager {
 	m.config = NewAuthConfig()
 
 	// 初始化Session存储
-	if sessionStore == nil {
+	if defaultSessionStore == nil {
 		InitSessionStore(config)
 	}
 
 	m.config.SetAuthFunc(func(c *gyarn.Context) bool {
 		// 获取session
-		session, err := sessionStore.Get(c.Request, "session")
-		if err != nil {
-			return false
-		}
+		session := GetSession(c)
 
 		// 检查用户是否已登录
 		userID, ok := session.Values["user_id"]
@@ -216,35 +232,25 @@ func GenerateJWT(config *JWTConfig, userID string, username string, role string)
 }
 
 // SetSession 设置Session
+// 登录成功后调用，会更换会话ID以防止会话固定攻击
 func SetSession(c *gyarn.Context, config *SessionConfig, userID int64, username, role string) error {
-	session, err := sessionStore.Get(c.Request, "session")
-	if err != nil {
-		return err
-	}
-
-	session.Values["user_id"] = userID
-	session.Values["username"] = username
-	session.Values["role"] = role
-	session.Options = &sessions.Options{
-		Path:     config.Path,
-		Domain:   config.Domain,
-		MaxAge:   config.MaxAge,
-		Secure:   config.Secure,
-		HttpOnly: config.HttpOnly,
+	if defaultSessionStore == nil {
+		InitSessionStore(config)
 	}
-
-	return session.Save(c.Request, c.Writer)
+	session := GetSession(c)
+	session.Regenerate()
+	session.Set("user_id", userID)
+	session.Set("username", username)
+	session.Set("role", role)
+	session.Options = config.options()
+	return session.Save()
 }
 
 // ClearSession 清除Session
 func ClearSession(c *gyarn.Context) error {
-	session, err := sessionStore.Get(c.Request, "session")
-	if err != nil {
-		return err
-	}
-
-	session.Options.MaxAge = -1
-	return session.Save(c.Request, c.Writer)
+	session := GetSession(c)
+	session.Destroy()
+	return session.Save()
 }
 
 // UseBasic 使用Basic认证
@@ -301,10 +307,7 @@ func CreateAuthMiddleware(config *AuthConfig) gyarn.HandlerFunc {
 	// 设置默认的未认证处理函数
 	if config.UnauthorizedHandler == nil {
 		config.UnauthorizedHandler = func(c *gyarn.Context) {
-			c.JSON(http.StatusUnauthorized, map[string]interface{}{
-				"code":    401,
-				"message": "未授权访问",
-			})
+			c.Unauthorized("")
 		}
 	}
 
@@ -0,0 +1,375 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+// 内容编码
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate" // HTTP 中的 deflate 为 zlib 格式（RFC 1950）
+)
+
+// compressionDisabledKey DisableCompression 在 Context 中的 key
+const compressionDisabledKey = "gyweb.nocompress"
+
+// CompressConfig 压缩中间件配置
+type CompressConfig struct {
+	// Level 压缩级别，取值同 compress/gzip，默认 gzip.DefaultCompression
+	Level int
+	// MinLength 响应体小于该字节数时不压缩，默认 1024
+	MinLength int
+	// Encodings 支持的编码，按优先级排列，默认 gzip、deflate
+	Encodings []string
+	// ContentTypes 允许压缩的内容类型，支持 "text/*" 形式的通配；为空时压缩除 ExcludedContentTypes 以外的所有类型
+	ContentTypes []string
+	// ExcludedContentTypes 不压缩的内容类型，优先于 ContentTypes，默认为图片、音视频、压缩包等已压缩的类型和 SSE
+	ExcludedContentTypes []string
+	// Skip 返回 true 时不压缩
+	Skip func(*gyarn.Context) bool
+}
+
+// DefaultCompressConfig 返回默认配置：压缩大于 1KB 的文本、JSON、JavaScript、XML、SVG 等响应
+func DefaultCompressConfig() *CompressConfig {
+	return &CompressConfig{
+		Level:     gzip.DefaultCompression,
+		MinLength: 1024,
+		Encodings: []string{EncodingGzip, EncodingDeflate},
+		ContentTypes: []string{
+			"text/*",
+			"application/json", "application/*+json", "application/problem+json",
+			"application/javascript", "application/x-javascript", "application/ecmascript",
+			"application/xml", "application/*+xml",
+			"application/wasm", "application/x-yaml",
+			"image/svg+xml", "image/x-icon", "font/ttf", "font/otf",
+		},
+		ExcludedContentTypes: []string{
+			"text/event-stream",
+			"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+			"audio/*", "video/*", "font/woff", "font/woff2",
+			"application/zip", "application/gzip", "application/x-gzip", "application/x-7z-compressed",
+			"application/x-rar-compressed", "application/x-bzip2", "application/zstd", "application/pdf",
+			"application/octet-stream",
+		},
+	}
+}
+
+// Gzip 压缩中间件，使用指定的压缩级别（如 gzip.BestSpeed），按 Accept-Encoding 协商 gzip 或 deflate
+func Gzip(level int) HandlerFunc {
+	config := DefaultCompressConfig()
+	config.Level = level
+	return CompressWithConfig(config)
+}
+
+// CompressWithConfig 压缩中间件，是否压缩在响应头发送前根据状态码、内容类型和长度决定
+// 已设置 Content-Encoding 的响应（如预压缩的静态文件）、Range 请求、HEAD 请求和 WebSocket 升级请求不会被压缩；
+// 可压缩的响应都会带上 Vary: Accept-Encoding，以免缓存把压缩后的内容返回给不支持的客户端
+//
+//	r.Use(middleware.CompressWithConfig(&middleware.CompressConfig{
+//		Level:        gzip.BestSpeed,
+//		MinLength:    2048,
+//		ContentTypes: []string{"application/json", "text/*"},
+//	}))
+func CompressWithConfig(config *CompressConfig) HandlerFunc {
+	if config == nil {
+		config = DefaultCompressConfig()
+	}
+	cfg := *config
+	if cfg.MinLength < 0 {
+		cfg.MinLength = 0
+	}
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = []string{EncodingGzip, EncodingDeflate}
+	}
+	if cfg.Level < gzip.HuffmanOnly || cfg.Level > gzip.BestCompression {
+		panic("middleware: 无效的压缩级别 " + strconv.Itoa(cfg.Level))
+	}
+	pools := make(map[string]*sync.Pool, len(cfg.Encodings))
+	for _, enc := range cfg.Encodings {
+		switch enc {
+		case EncodingGzip:
+			pools[enc] = &sync.Pool{New: func() any {
+				w, _ := gzip.NewWriterLevel(io.Discard, cfg.Level)
+				return w
+			}}
+		case EncodingDeflate:
+			pools[enc] = &sync.Pool{New: func() any {
+				w, _ := zlib.NewWriterLevel(io.Discard, cfg.Level)
+				return w
+			}}
+		default:
+			panic("middleware: 不支持的压缩编码 " + enc)
+		}
+	}
+
+	return func(c *gyarn.Context) {
+		req := c.Request
+		if req.Method == http.MethodHead || req.Header.Get("Upgrade") != "" || req.Header.Get("Range") != "" ||
+			(cfg.Skip != nil && cfg.Skip(c)) {
+			c.Next()
+			return
+		}
+
+		encoding := NegotiateEncoding(req.Header.Get("Accept-Encoding"), cfg.Encodings...)
+		cw := &compressWriter{ResponseWriter: c.Writer, c: c, config: &cfg, encoding: encoding}
+		if encoding != "" {
+			cw.pool = pools[encoding]
+		}
+		orig := c.Writer
+		c.Writer = cw
+		defer func() {
+			c.Writer = orig
+			if r := recover(); r != nil {
+				cw.release()
+				panic(r)
+			}
+			cw.close()
+		}()
+		c.Next()
+	}
+}
+
+// DisableCompression 使当前请求的响应不被压缩，需要在响应头发送前调用
+func DisableCompression(c *gyarn.Context) {
+	c.Set(compressionDisabledKey, true)
+}
+
+// NegotiateEncoding 按 Accept-Encoding 从 offers 中选出客户端最优先接受的编码，都不接受时返回空字符串
+// q 值相同时按 offers 的顺序选择
+func NegotiateEncoding(acceptEncoding string, offers ...string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, wildcard := -1.0, -1.0
+		for _, part := range strings.Split(acceptEncoding, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != offer && name != "*" {
+				continue
+			}
+			value := 1.0
+			if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					value = f
+				}
+			}
+			if name == "*" {
+				wildcard = value
+			} else {
+				q = value
+			}
+		}
+		if q < 0 {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// compressor gzip.Writer 和 zlib.Writer 的公共方法
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressWriter 在第一次输出时决定是否压缩；决定之前最多缓冲 MinLength 字节
+type compressWriter struct {
+	gyarn.ResponseWriter
+	c        *gyarn.Context
+	config   *CompressConfig
+	encoding string
+	pool     *sync.Pool
+	buf      []byte
+	decided  bool
+	cw       compressor
+}
+
+var _ gyarn.ResponseWriter = (*compressWriter)(nil)
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.config.MinLength {
+			return len(data), nil
+		}
+		if err := w.start(false); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.cw != nil {
+		return w.cw.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteHeaderNow 立即发送响应头，此时按已缓冲的数据决定是否压缩
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.start(false)
+	}
+}
+
+// Written 已缓冲数据时也视为已发送，避免错误处理在部分输出之后再追加内容
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.start(false)
+	}
+	if w.cw != nil {
+		w.cw.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// start 决定是否压缩并写出已缓冲的数据；final 表示响应已经结束，缓冲的数据就是完整的响应体
+func (w *compressWriter) start(final bool) error {
+	w.decided = true
+	if w.compressible(final) {
+		cw := w.pool.Get().(compressor)
+		cw.Reset(w.ResponseWriter)
+		w.cw = cw
+		header := w.Header()
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", w.encoding)
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// 压缩后的内容与原内容不同，强 ETag 改为弱 ETag
+			header.Set("ETag", "W/"+etag)
+		}
+	}
+	if len(w.buf) == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return nil
+	}
+	var err error
+	if w.cw != nil {
+		_, err = w.cw.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// compressible 判断响应是否需要压缩，可压缩的类型同时加上 Vary: Accept-Encoding
+func (w *compressWriter) compressible(final bool) bool {
+	if w.ResponseWriter.Written() {
+		return false
+	}
+	if disabled, _ := w.c.Get(compressionDisabledKey); disabled == true {
+		return false
+	}
+	status := w.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusPartialContent ||
+		status == http.StatusNotModified {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" && len(w.buf) > 0 {
+		// 与 net/http 相同，按内容识别类型
+		contentType = http.DetectContentType(w.buf)
+		header.Set("Content-Type", contentType)
+	}
+	if !w.config.allowType(contentType) {
+		return false
+	}
+	addVary(header, "Accept-Encoding")
+
+	if w.encoding == "" {
+		return false
+	}
+	if final && len(w.buf) < w.config.MinLength {
+		return false
+	}
+	if n, err := strconv.Atoi(header.Get("Content-Length")); err == nil && n < w.config.MinLength {
+		return false
+	}
+	return true
+}
+
+// allowType 判断内容类型是否允许压缩
+func (config *CompressConfig) allowType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if matchMediaType(config.ExcludedContentTypes, mediaType) {
+		return false
+	}
+	return len(config.ContentTypes) == 0 || matchMediaType(config.ContentTypes, mediaType)
+}
+
+// matchMediaType 支持 "text/*"、"application/*+json" 形式的通配
+func matchMediaType(patterns []string, mediaType string) bool {
+	for _, pattern := range patterns {
+		prefix, suffix, wildcard := strings.Cut(strings.ToLower(pattern), "*")
+		if !wildcard {
+			if prefix == mediaType {
+				return true
+			}
+			continue
+		}
+		if len(mediaType) >= len(prefix)+len(suffix) && strings.HasPrefix(mediaType, prefix) &&
+			strings.HasSuffix(mediaType, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// close 处理链结束后写出剩余数据并结束压缩流
+func (w *compressWriter) close() {
+	if !w.decided {
+		if len(w.buf) == 0 && !w.ResponseWriter.Written() {
+			// 没有响应体：不压缩，响应头由引擎发送，但可压缩的类型仍需要 Vary
+			w.decided = true
+			w.compressible(true)
+			return
+		}
+		w.start(true)
+	}
+	if w.cw != nil {
+		w.cw.Close()
+		w.release()
+	}
+}
+
+// release 把压缩器放回对象池
+func (w *compressWriter) release() {
+	if w.cw != nil {
+		w.cw.Reset(io.Discard)
+		w.pool.Put(w.cw)
+		w.cw = nil
+	}
+}
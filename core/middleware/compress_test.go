@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+// serveCompressed 用压缩中间件处理一次请求
+func serveCompressed(handler HandlerFunc, acceptEncoding string, fn gyarn.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	return serve(req, handler, fn)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"gzip, deflate, br", "gzip"},
+		{"deflate;q=1.0, gzip;q=0.5", "deflate"},
+		{"gzip;q=0, deflate", "deflate"},
+		{"*", "gzip"},
+		{"*;q=0.5, gzip;q=0", "deflate"},
+		{"br", ""},
+	}
+	for _, tc := range cases {
+		if got := NegotiateEncoding(tc.accept, EncodingGzip, EncodingDeflate); got != tc.want {
+			t.Errorf("NegotiateEncoding(%q) = %q，期望 %q", tc.accept, got, tc.want)
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	body := strings.Repeat(`{"name":"gyweb"}`, 200)
+	w := serveCompressed(Gzip(gzip.BestSpeed), "gzip", func(c *gyarn.Context) {
+		c.SetHeader("Content-Type", "application/json")
+		c.SetHeader("Content-Length", "3200")
+		c.String(http.StatusOK, body)
+	})
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" ||
+		w.Header().Get("Content-Length") != "" {
+		t.Fatalf("响应头不正确: %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != body {
+		t.Fatalf("解压后的内容不一致，长度 %d", len(data))
+	}
+}
+
+func TestDeflate(t *testing.T) {
+	body := strings.Repeat("hello ", 500)
+	w := serveCompressed(Gzip(gzip.DefaultCompression), "deflate", func(c *gyarn.Context) {
+		c.String(http.StatusOK, body)
+	})
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("应使用 deflate: %v", w.Header())
+	}
+	zr, err := zlib.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != body {
+		t.Fatal("解压后的内容不一致")
+	}
+}
+
+func TestCompressSkipped(t *testing.T) {
+	handler := Gzip(gzip.DefaultCompression)
+	large := strings.Repeat("a", 4096)
+	cases := []struct {
+		name   string
+		accept string
+		fn     gyarn.HandlerFunc
+		vary   bool
+	}{
+		{"小于 MinLength", "gzip", func(c *gyarn.Context) { c.String(http.StatusOK, "short") }, true},
+		{"客户端不支持", "", func(c *gyarn.Context) { c.String(http.StatusOK, large) }, true},
+		{"已压缩的类型", "gzip", func(c *gyarn.Context) {
+			c.SetHeader("Content-Type", "image/png")
+			c.Writer.Write([]byte(large))
+		}, false},
+		{"SSE", "gzip", func(c *gyarn.Context) {
+			c.SetHeader("Content-Type", "text/event-stream")
+			c.Writer.Write([]byte(large))
+		}, false},
+		{"已有 Content-Encoding", "gzip", func(c *gyarn.Context) {
+			c.SetHeader("Content-Encoding", "br")
+			c.String(http.StatusOK, large)
+		}, false},
+		{"DisableCompression", "gzip", func(c *gyarn.Context) {
+			DisableCompression(c)
+			c.String(http.StatusOK, large)
+		}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serveCompressed(handler, tc.accept, tc.fn)
+			if enc := w.Header().Get("Content-Encoding"); enc == "gzip" {
+				t.Fatalf("不应压缩: %v", w.Header())
+			}
+			if got := w.Header().Get("Vary") == "Accept-Encoding"; got != tc.vary {
+				t.Fatalf("Vary = %q", w.Header().Get("Vary"))
+			}
+			if w.Body.Len() == 0 {
+				t.Fatal("响应体丢失")
+			}
+		})
+	}
+}
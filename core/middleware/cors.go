@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/logger"
+)
+
+// CORSConfig 跨域中间件配置
+type CORSConfig struct {
+	// AllowOrigins 允许的来源，支持精确匹配（"https://example.com"）、通配子域名（"https://*.example.com"）
+	// 和 "*"（允许所有来源）；不区分大小写
+	AllowOrigins []string
+	// AllowOriginPatterns 允许的来源正则表达式，如 `^https://[a-z]+\.example\.com$`
+	AllowOriginPatterns []string
+	// AllowOriginFunc 自定义来源校验，在上面两项都不匹配时调用
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods 允许的请求方法
+	AllowMethods []string
+	// AllowHeaders 允许的请求头，为空时按预检请求的 Access-Control-Request-Headers 原样允许
+	AllowHeaders []string
+	// ExposeHeaders 允许浏览器脚本读取的响应头
+	ExposeHeaders []string
+	// AllowCredentials 是否允许携带 cookie 等凭据；开启时即使 AllowOrigins 为 "*" 也会返回具体的来源，
+	// 因为浏览器不接受 Access-Control-Allow-Origin: * 与凭据同时出现
+	AllowCredentials bool
+	// MaxAge 预检结果的缓存时间，0 表示不设置
+	MaxAge time.Duration
+	// AllowPrivateNetwork 是否允许公网页面访问内网地址（Private Network Access 预检）
+	AllowPrivateNetwork bool
+}
+
+// DefaultCORSConfig 返回默认配置：允许所有来源并允许携带凭据，与 CORS() 的行为一致
+// 此时会原样回显任意请求的 Origin 并允许携带 cookie，任何网站都能以用户身份调用接口；
+// 生产环境应设置 AllowOrigins 为具体的来源
+func DefaultCORSConfig() *CORSConfig {
+	return &CORSConfig{
+		AllowOrigins:     []string{"*"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-Custom-Header", "Token"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Type", "Authorization"},
+		AllowCredentials: true,
+		MaxAge:           24 * time.Hour,
+	}
+}
+
+// CORS 使用默认配置的跨域中间件，仅适用于本地开发
+//
+// 警告：默认配置允许任意来源携带凭据访问，使用 cookie 或 HTTP 认证的服务会受到跨站请求攻击，
+// 生产环境请使用 CORSWithConfig 并设置具体的 AllowOrigins
+func CORS() HandlerFunc {
+	return CORSWithConfig(DefaultCORSConfig())
+}
+
+// CORSWithConfig 跨域中间件，应在认证等中间件之前注册，使预检请求不需要认证
+// 预检请求（带 Access-Control-Request-Method 的 OPTIONS）在这里直接以 204 响应，来源不被允许时返回 403；
+// 来源不被允许的普通请求照常处理但不带跨域响应头，由浏览器拦截
+//
+//	r.Use(middleware.CORSWithConfig(&middleware.CORSConfig{
+//		AllowOrigins:     []string{"https://admin.example.com", "https://*.example.com"},
+//		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
+//		AllowCredentials: true,
+//		MaxAge:           12 * time.Hour,
+//	}))
+func CORSWithConfig(config *CORSConfig) HandlerFunc {
+	if config == nil {
+		config = DefaultCORSConfig()
+	}
+	m := newOriginMatcher(config)
+	allowMethods := strings.Join(config.AllowMethods, ", ")
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+	maxAge := ""
+	if config.MaxAge > 0 {
+		maxAge = strconv.FormatInt(int64(config.MaxAge/time.Second), 10)
+	}
+	// 允许所有来源且不带凭据时响应 *，响应与来源无关，不需要 Vary: Origin
+	wildcard := m.any && !config.AllowCredentials
+
+	return func(c *gyarn.Context) {
+		header := c.Writer.Header()
+		origin := c.GetHeader("Origin")
+		preflight := c.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if !wildcard {
+			addVary(header, "Origin")
+		}
+		if origin == "" {
+			// 不是跨域请求
+			c.Next()
+			return
+		}
+
+		logger.Debug("[CORS] 处理请求", "method", c.Method, "path", c.Path, "origin", origin, "preflight", preflight)
+		if !m.allowed(origin) {
+			logger.Debug("[CORS] 来源不被允许", "origin", origin)
+			if preflight {
+				c.Abort()
+				c.Status(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if wildcard {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		addVary(header, "Access-Control-Request-Method")
+		if allowMethods != "" {
+			header.Set("Access-Control-Allow-Methods", allowMethods)
+		}
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+			addVary(header, "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if maxAge != "" {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		if config.AllowPrivateNetwork && c.GetHeader("Access-Control-Request-Private-Network") == "true" {
+			header.Set("Access-Control-Allow-Private-Network", "true")
+		}
+		logger.Debug("[CORS] 预检请求已响应", "origin", origin,
+			"method", c.GetHeader("Access-Control-Request-Method"), "headers", c.GetHeader("Access-Control-Request-Headers"))
+		c.Abort()
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// originMatcher 预先整理好的来源规则
+type originMatcher struct {
+	any      bool
+	exact    map[string]bool
+	wildcard [][2]string // 通配子域名拆分后的前缀和后缀，如 {"https://", ".example.com"}
+	patterns []*regexp.Regexp
+	fn       func(string) bool
+}
+
+func newOriginMatcher(config *CORSConfig) *originMatcher {
+	m := &originMatcher{exact: make(map[string]bool), fn: config.AllowOriginFunc}
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "*":
+			m.any = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			m.wildcard = append(m.wildcard, [2]string{prefix, suffix})
+		default:
+			m.exact[strings.TrimSuffix(origin, "/")] = true
+		}
+	}
+	for _, pattern := range config.AllowOriginPatterns {
+		m.patterns = append(m.patterns, regexp.MustCompile(pattern))
+	}
+	return m
+}
+
+// allowed 判断来源是否被允许
+func (m *originMatcher) allowed(origin string) bool {
+	if m.any {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if m.exact[lower] {
+		return true
+	}
+	for _, w := range m.wildcard {
+		// 通配部分至少包含一个字符且不能包含 /，"https://*.example.com" 不匹配 "https://.example.com"
+		if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) &&
+			!strings.Contains(lower[len(w[0]):len(lower)-len(w[1])], "/") {
+			return true
+		}
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return m.fn != nil && m.fn(origin)
+}
+
+// addVary 添加 Vary 响应头，已存在时不重复添加
+func addVary(header http.Header, value string) {
+	for _, v := range header.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), value) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
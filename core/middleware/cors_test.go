@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func runCORS(config *CORSConfig, method string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return serve(req, CORSWithConfig(config), respondOK)
+}
+
+func TestCORSOrigins(t *testing.T) {
+	config := &CORSConfig{
+		AllowOrigins:        []string{"https://admin.example.com", "https://*.example.org"},
+		AllowOriginPatterns: []string{`^http://localhost:\d+$`},
+		AllowOriginFunc:     func(origin string) bool { return origin == "https://partner.io" },
+		AllowCredentials:    true,
+	}
+	tests := []struct {
+		origin string
+		allow  bool
+	}{
+		{"https://admin.example.com", true},
+		{"https://ADMIN.example.com", true},
+		{"https://evil.com", false},
+		{"https://a.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://.example.org", false},
+		{"https://example.org", false},
+		{"http://a.example.org", false},
+		{"http://localhost:5173", true},
+		{"https://partner.io", true},
+	}
+	for _, tt := range tests {
+		w := runCORS(config, "GET", map[string]string{"Origin": tt.origin})
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.allow && got != tt.origin || !tt.allow && got != "" {
+			t.Errorf("Origin %s: Access-Control-Allow-Origin = %q", tt.origin, got)
+		}
+		if w.Code != http.StatusOK {
+			t.Errorf("Origin %s: 普通请求应继续处理，状态码 %d", tt.origin, w.Code)
+		}
+		if w.Header().Get("Vary") != "Origin" {
+			t.Errorf("Origin %s: 缺少 Vary: Origin", tt.origin)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	config := &CORSConfig{
+		AllowOrigins:        []string{"https://app.example.com"},
+		AllowMethods:        []string{"GET", "POST"},
+		MaxAge:              time.Hour,
+		AllowPrivateNetwork: true,
+	}
+	w := runCORS(config, "OPTIONS", map[string]string{
+		"Origin":                                 "https://app.example.com",
+		"Access-Control-Request-Method":          "POST",
+		"Access-Control-Request-Headers":         "X-Token",
+		"Access-Control-Request-Private-Network": "true",
+	})
+	h := w.Header()
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Fatalf("预检请求应返回 204，实际 %d %q", w.Code, w.Body.String())
+	}
+	if h.Get("Access-Control-Allow-Methods") != "GET, POST" || h.Get("Access-Control-Allow-Headers") != "X-Token" ||
+		h.Get("Access-Control-Max-Age") != "3600" || h.Get("Access-Control-Allow-Private-Network") != "true" ||
+		h.Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatalf("预检响应头不正确: %v", h)
+	}
+
+	w = runCORS(config, "OPTIONS", map[string]string{"Origin": "https://evil.com", "Access-Control-Request-Method": "POST"})
+	if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("不允许的来源预检应返回 403，实际 %d", w.Code)
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	w := runCORS(&CORSConfig{AllowOrigins: []string{"*"}}, "GET", map[string]string{"Origin": "https://a.com"})
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Vary") != "" {
+		t.Fatalf("不带凭据时应返回 * 且不需要 Vary: %v", w.Header())
+	}
+	w = runCORS(DefaultCORSConfig(), "GET", map[string]string{"Origin": "https://a.com"})
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://a.com" || w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("带凭据时不能返回 *: %v", w.Header())
+	}
+}
@@ -1,33 +1,51 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"os"
-	"sync/atomic"
+	"sync"
 
 	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/logger"
 )
 
-var (
-	// debugEnabled 调试模式开关
-	debugEnabled uint32
-	// debugLogger 调试日志记录器
-	debugLogger = log.New(os.Stdout, "[GYWEB-DEBUG] ", log.LstdFlags|log.Lshortfile)
-)
+// debugMode SetDebug 开启调试模式前默认日志器的级别
+var debugMode struct {
+	sync.Mutex
+	enabled bool
+	saved   logger.Level
+}
 
 // SetDebug 设置调试模式
+// 开启时把框架默认日志器降到 Debug 级别，关闭时恢复开启前的级别；
+// 只撤销 SetDebug(true) 的修改，不会覆盖通过 logger.SetLevel 设置的级别
 func SetDebug(enable bool) {
+	debugMode.Lock()
+	defer debugMode.Unlock()
+	level, ok := logger.GetLevel()
+	if !ok {
+		return
+	}
 	if enable {
-		atomic.StoreUint32(&debugEnabled, 1)
-	} else {
-		atomic.StoreUint32(&debugEnabled, 0)
+		if !debugMode.enabled && level > logger.LevelDebug {
+			debugMode.enabled, debugMode.saved = true, level
+			logger.SetLevel(logger.LevelDebug)
+		}
+		return
+	}
+	if debugMode.enabled {
+		debugMode.enabled = false
+		// 期间通过 logger.SetLevel 改过级别时以新设置为准
+		if level == logger.LevelDebug {
+			logger.SetLevel(debugMode.saved)
+		}
 	}
 }
 
 // IsDebugEnabled 检查调试模式是否启用
 func IsDebugEnabled() bool {
-	return atomic.LoadUint32(&debugEnabled) == 1
+	return logger.Enabled(logger.LevelDebug)
 }
 
 // init 初始化调试模式
@@ -41,68 +59,85 @@ func init() {
 // debugLog 输出调试日志
 func debugLog(format string, args ...interface{}) {
 	if IsDebugEnabled() {
-		debugLogger.Output(2, fmt.Sprintf(format, args...))
+		logger.LogDepth(1, logger.LevelDebug, fmt.Sprintf(format, args...))
 	}
 }
 
 // debugAuth 输出认证相关的调试信息
 func debugAuth(c *gyarn.Context, msg string, args ...interface{}) {
 	if IsDebugEnabled() {
-		debugLogger.Output(2, fmt.Sprintf("[Auth] %s - %s %s: %s",
-			c.Request.RemoteAddr,
-			c.Request.Method,
-			c.Request.URL.Path,
-			fmt.Sprintf(msg, args...),
-		))
+		logger.LogDepth(1, logger.LevelDebug, "[Auth] "+fmt.Sprintf(msg, args...),
+			"remote", c.Request.RemoteAddr,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+		)
 	}
 }
 
 // debugWhitelist 输出白名单匹配的调试信息
 func debugWhitelist(path string, matched bool, matchType string) {
 	if IsDebugEnabled() {
-		debugLogger.Output(2, fmt.Sprintf("[Whitelist] Path: %s, Type: %s, Matched: %v",
-			path,
-			matchType,
-			matched,
-		))
+		logger.LogDepth(1, logger.LevelDebug, "[Whitelist]",
+			"path", path,
+			"type", matchType,
+			"matched", matched,
+		)
 	}
 }
 
 // debugAuthFunc 输出认证函数执行的调试信息
 func debugAuthFunc(c *gyarn.Context, success bool) {
 	if IsDebugEnabled() {
-		debugLogger.Output(2, fmt.Sprintf("[AuthFunc] %s - %s %s: %v",
-			c.Request.RemoteAddr,
-			c.Request.Method,
-			c.Request.URL.Path,
-			success,
-		))
+		logger.LogDepth(1, logger.LevelDebug, "[AuthFunc]",
+			"remote", c.Request.RemoteAddr,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"success", success,
+		)
 	}
 }
 
 // debugUnauthorized 输出未授权处理的调试信息
 func debugUnauthorized(c *gyarn.Context) {
 	if IsDebugEnabled() {
-		debugLogger.Output(2, fmt.Sprintf("[Unauthorized] %s - %s %s",
-			c.Request.RemoteAddr,
-			c.Request.Method,
-			c.Request.URL.Path,
-		))
+		logger.LogDepth(1, logger.LevelDebug, "[Unauthorized]",
+			"remote", c.Request.RemoteAddr,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+		)
 	}
 }
 
 // DebugSQL 输出 SQL 语句和参数的调试信息
 func DebugSQL(sql string, args ...interface{}) {
 	if IsDebugEnabled() {
-		debugLogger.Output(2, fmt.Sprintf("[SQL] Query: %s\nArgs: %v",
-			sql,
-			args,
-		))
+		logger.LogDepth(1, logger.LevelDebug, "[SQL]",
+			"query", sql,
+			"args", args,
+		)
+	}
+}
+
+// DebugSQLContext 与 DebugSQL 相同，ctx 中有请求 ID 时一并输出；ctx 可以为 nil
+func DebugSQLContext(ctx context.Context, sql string, args ...interface{}) {
+	if IsDebugEnabled() {
+		if id := RequestIDFromContext(ctx); id != "" {
+			logger.LogDepth(1, logger.LevelDebug, "[SQL]",
+				"query", sql,
+				"args", args,
+				"request_id", id,
+			)
+			return
+		}
+		logger.LogDepth(1, logger.LevelDebug, "[SQL]",
+			"query", sql,
+			"args", args,
+		)
 	}
 }
 
 func DebugVar(varname string, v interface{}) {
 	if IsDebugEnabled() {
-		debugLogger.Output(2, fmt.Sprintf("[VAR] %s: %v", varname, v))
+		logger.LogDepth(1, logger.LevelDebug, "[VAR]", varname, fmt.Sprint(v))
 	}
 }
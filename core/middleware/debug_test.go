@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/guyigood/gyweb/core/logger"
+)
+
+func TestSetDebugRestoresLevel(t *testing.T) {
+	prev := logger.Default()
+	defer logger.SetDefault(prev)
+	logger.SetDefault(logger.New(logger.Config{Level: logger.LevelWarn}))
+
+	// 关闭调试模式不应覆盖已配置的级别
+	SetDebug(false)
+	if level, _ := logger.GetLevel(); level != logger.LevelWarn {
+		t.Fatalf("SetDebug(false) 把级别改成了 %v", level)
+	}
+
+	SetDebug(true)
+	if !IsDebugEnabled() {
+		t.Fatal("SetDebug(true) 应开启 Debug 级别")
+	}
+	SetDebug(false)
+	if level, _ := logger.GetLevel(); level != logger.LevelWarn {
+		t.Fatalf("关闭调试模式后级别为 %v，期望恢复为 WARN", level)
+	}
+
+	// 调试期间通过 logger.SetLevel 修改的级别优先
+	SetDebug(true)
+	logger.SetLevel(logger.LevelError)
+	SetDebug(false)
+	if level, _ := logger.GetLevel(); level != logger.LevelError {
+		t.Fatalf("级别为 %v，期望保留 logger.SetLevel 设置的 ERROR", level)
+	}
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/logger"
+)
+
+// 错误响应格式
+const (
+	ErrorFormatEnvelope = "envelope" // gyarn.Response 结构：{"code":400,"message":"...","data":...}
+	ErrorFormatProblem  = "problem"  // RFC 7807 application/problem+json
+)
+
+// MIMEProblemJSON RFC 7807 的内容类型
+const MIMEProblemJSON = "application/problem+json"
+
+// ErrorHandlerConfig 错误处理中间件配置
+type ErrorHandlerConfig struct {
+	// Format 响应格式：envelope 或 problem，默认 envelope
+	Format string
+	// ProblemTypeBase problem 格式中 type 字段的前缀，type 为 前缀/业务码；为空时使用 about:blank
+	ProblemTypeBase string
+	// ShowInternalErrors 5xx 错误是否把原始错误信息放在 details 中返回，建议只在开发环境开启
+	ShowInternalErrors bool
+	// Render 自定义渲染函数，设置后忽略 Format
+	Render gyarn.ErrorRenderer
+}
+
+// ProblemDetails RFC 7807 响应结构，code 和 details 为扩展字段
+type ProblemDetails struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Code     int         `json:"code"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// DefaultErrorHandlerConfig 返回默认配置
+func DefaultErrorHandlerConfig() *ErrorHandlerConfig {
+	return &ErrorHandlerConfig{
+		Format: ErrorFormatEnvelope,
+	}
+}
+
+// ErrorHandler 使用默认配置的错误处理中间件
+func ErrorHandler() HandlerFunc {
+	return ErrorHandlerWithConfig(DefaultErrorHandlerConfig())
+}
+
+// ErrorHandlerWithConfig 错误处理中间件，应在其他中间件之前注册
+//
+// 安装后 c.Error、c.BadRequest、c.Fail、c.AbortWithError 等方法都通过同一个渲染器输出；
+// 处理链结束时如果记录了错误（c.AddError）但还没有写出响应，输出最后一个错误；5xx 错误会记录日志
+func ErrorHandlerWithConfig(config *ErrorHandlerConfig) HandlerFunc {
+	if config == nil {
+		config = DefaultErrorHandlerConfig()
+	}
+	render := config.Render
+	if render == nil {
+		if strings.EqualFold(config.Format, ErrorFormatProblem) {
+			render = problemRenderer(config.ProblemTypeBase)
+		} else {
+			render = gyarn.RenderEnvelope
+		}
+	}
+	if config.ShowInternalErrors {
+		base := render
+		render = func(c *gyarn.Context, e *gyarn.HTTPError) {
+			if e.Status >= http.StatusInternalServerError && e.Err != nil && e.Details == nil {
+				e = e.WithDetails(e.Err.Error())
+			}
+			base(c, e)
+		}
+	}
+
+	return func(c *gyarn.Context) {
+		c.SetErrorRenderer(render)
+		c.Next()
+
+		for _, err := range c.Errors {
+			if e := gyarn.ToHTTPError(err); e.Status >= http.StatusInternalServerError {
+				logger.Error("请求处理出错",
+					"method", c.Method,
+					"path", c.Request.URL.Path,
+					"status", e.Status,
+					"error", err,
+				)
+			}
+		}
+		if err := c.LastError(); err != nil && !c.Writer.Written() {
+			c.RenderError(err)
+		}
+	}
+}
+
+// problemRenderer 以 RFC 7807 格式输出错误
+func problemRenderer(typeBase string) gyarn.ErrorRenderer {
+	typeBase = strings.TrimSuffix(typeBase, "/")
+	return func(c *gyarn.Context, e *gyarn.HTTPError) {
+		status := problemStatus(e)
+		problem := ProblemDetails{
+			Type:     "about:blank",
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   e.Message,
+			Instance: c.Request.URL.Path,
+			Code:     e.Code,
+			Details:  e.Details,
+		}
+		if typeBase != "" {
+			problem.Type = typeBase + "/" + strconv.Itoa(e.Code)
+		}
+		body, err := json.Marshal(problem)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.Data(status, MIMEProblemJSON, body)
+	}
+}
+
+// problemStatus problem 格式必须使用错误状态码：
+// c.Error 等业务错误的 HTTP 状态码为 200，此时业务码是 4xx/5xx 则使用业务码，否则按 400 处理
+func problemStatus(e *gyarn.HTTPError) int {
+	if e.Status >= http.StatusBadRequest {
+		return e.Status
+	}
+	if e.Code >= http.StatusBadRequest && e.Code <= 599 {
+		return e.Code
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/binding"
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+func TestErrorHandlerEnvelope(t *testing.T) {
+	w := serve(httptest.NewRequest("GET", "/orders/1", nil), ErrorHandler(), func(c *gyarn.Context) {
+		c.AbortWithError(gyarn.ErrNotFound.WithCode(40401).WithMessage("订单不存在"))
+	})
+	if w.Code != http.StatusNotFound || !strings.HasPrefix(w.Header().Get("Content-Type"), gyarn.MIMEJSON) {
+		t.Fatalf("状态码 %d，Content-Type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != `{"code":40401,"message":"订单不存在"}` {
+		t.Fatalf("响应 = %s", body)
+	}
+}
+
+func TestErrorHandlerProblem(t *testing.T) {
+	handler := ErrorHandlerWithConfig(&ErrorHandlerConfig{Format: ErrorFormatProblem, ProblemTypeBase: "https://errors.example.com/"})
+	problem := func(fn gyarn.HandlerFunc) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := serve(httptest.NewRequest("POST", "/users", nil), handler, fn)
+		if ct := w.Header().Get("Content-Type"); ct != MIMEProblemJSON {
+			t.Fatalf("Content-Type = %q，期望 %s", ct, MIMEProblemJSON)
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return w, body
+	}
+
+	w, body := problem(func(c *gyarn.Context) {
+		c.AbortWithError(gyarn.ToHTTPError(binding.ValidationErrors{{Field: "email", Rule: "email"}}))
+	})
+	want := map[string]interface{}{
+		"type":     "https://errors.example.com/400",
+		"title":    "Bad Request",
+		"status":   float64(400),
+		"detail":   "参数校验失败",
+		"instance": "/users",
+		"code":     float64(400),
+	}
+	for k, v := range want {
+		if body[k] != v {
+			t.Errorf("%s = %v，期望 %v", k, body[k], v)
+		}
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("状态码 = %d，期望 400", w.Code)
+	}
+	details, _ := body["details"].([]interface{})
+	if len(details) != 1 {
+		t.Errorf("details 应包含字段错误: %v", body["details"])
+	}
+
+	// 业务错误的 HTTP 状态码为 200，problem 格式改用业务码或 400
+	if w, body = problem(func(c *gyarn.Context) { c.Error(http.StatusForbidden, "无权操作") }); w.Code != http.StatusForbidden || body["code"] != float64(403) {
+		t.Errorf("业务码为 4xx 时应作为状态码: %d %v", w.Code, body)
+	}
+	if w, body = problem(func(c *gyarn.Context) { c.Error(10001, "余额不足") }); w.Code != http.StatusBadRequest || body["code"] != float64(10001) {
+		t.Errorf("其他业务码应按 400 返回并保留 code: %d %v", w.Code, body)
+	}
+
+	noBase := ErrorHandlerWithConfig(&ErrorHandlerConfig{Format: ErrorFormatProblem})
+	w = serve(httptest.NewRequest("GET", "/", nil), noBase, func(c *gyarn.Context) { c.Unauthorized("") })
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), `"type":"about:blank"`) {
+		t.Errorf("未设置 ProblemTypeBase 时 type 应为 about:blank: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestErrorHandlerDeferredError(t *testing.T) {
+	cause := errors.New("dial tcp 10.0.0.5:3306: connection refused")
+	w := serve(httptest.NewRequest("GET", "/", nil), ErrorHandler(), func(c *gyarn.Context) {
+		c.AddError(cause)
+	})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("处理链结束后应输出记录的错误，状态码 %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "10.0.0.5") {
+		t.Fatalf("默认不应返回 5xx 的原始错误: %s", w.Body.String())
+	}
+
+	show := ErrorHandlerWithConfig(&ErrorHandlerConfig{ShowInternalErrors: true})
+	w = serve(httptest.NewRequest("GET", "/", nil), show, func(c *gyarn.Context) { c.AddError(cause) })
+	if !strings.Contains(w.Body.String(), "10.0.0.5") {
+		t.Fatalf("ShowInternalErrors 应在 data 中返回原始错误: %s", w.Body.String())
+	}
+
+	// 已写出响应时不再输出错误
+	w = serve(httptest.NewRequest("GET", "/", nil), ErrorHandler(), func(c *gyarn.Context) {
+		c.String(http.StatusOK, "ok")
+		c.AddError(cause)
+	})
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("响应已写出时不应追加错误: %d %q", w.Code, w.Body.String())
+	}
+}
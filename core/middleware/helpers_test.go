@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+// serve 依次执行 handlers 处理一次请求，返回记录的响应
+func serve(req *http.Request, handlers ...gyarn.HandlerFunc) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c := gyarn.NewContext(w, req)
+	c.Handlers = handlers
+	c.Next()
+	c.Writer.WriteHeaderNow()
+	return w
+}
+
+// respondOK 输出 200 和 "ok"
+func respondOK(c *gyarn.Context) {
+	c.String(http.StatusOK, "ok")
+}
@@ -0,0 +1,390 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/logger"
+)
+
+// 访问日志格式
+const (
+	LogFormatText     = ""         // 通过 core/logger 输出结构化日志，格式由框架日志配置决定
+	LogFormatCombined = "combined" // Apache combined 格式
+	LogFormatCommon   = "common"   // Apache common（CLF）格式
+	LogFormatJSON     = "json"     // 每行一个 JSON 对象
+)
+
+// Apache 格式对应的模板
+const (
+	commonLogTemplate   = `${ip} - ${user} [${time_clf}] "${method} ${uri} ${protocol}" ${status} ${size}`
+	combinedLogTemplate = commonLogTemplate + ` "${referer}" "${user_agent}"`
+)
+
+// LoggerConfig 访问日志中间件配置
+type LoggerConfig struct {
+	// Format 日志格式：LogFormatText、LogFormatCombined、LogFormatCommon、LogFormatJSON，
+	// 其他值作为自定义模板，如 "${time} ${status} ${method} ${uri} ${latency}"
+	//
+	// 模板变量：time、time_clf、time_unix、ip、remote_ip、host、method、path、route、uri、query、protocol、
+	// status、size、latency、latency_ms、user、user_agent、referer、error、request_id，以及 header:名称（请求头）；
+	// 值为空时输出 "-"，引号和控制字符会被转义
+	Format string
+	// Output 日志输出目标，默认 os.Stdout；LogFormatText 格式不使用，改用 Logger
+	// 可以是 logger.NewRotatingFile 创建的滚动文件，或用 io.MultiWriter 同时写入多个目标
+	Output io.Writer
+	// Logger LogFormatText 格式使用的日志器，默认为框架默认日志器
+	Logger logger.Logger
+	// TimeFormat 模板变量 time 和 JSON 格式的时间格式，默认 time.RFC3339
+	TimeFormat string
+	// SkipPaths 不记录日志的路径（精确匹配），如健康检查
+	SkipPaths []string
+	// Skip 返回 true 时不记录日志
+	Skip func(*gyarn.Context) bool
+	// SlowThreshold 慢请求阈值，超过时额外输出一条 Warn 日志，0 表示不检测
+	SlowThreshold time.Duration
+	// UserKey 登录用户在 Context 中的 key，默认 "login"；取值规则与 RateLimitByUser 相同
+	UserKey string
+}
+
+// DefaultLoggerConfig 返回默认配置：结构化日志，不跳过任何路径，不检测慢请求
+func DefaultLoggerConfig() *LoggerConfig {
+	return &LoggerConfig{
+		Format:     LogFormatText,
+		TimeFormat: time.RFC3339,
+		UserKey:    "login",
+	}
+}
+
+// Logger 访问日志中间件，使用默认配置
+func Logger() HandlerFunc {
+	return LoggerWithConfig(DefaultLoggerConfig())
+}
+
+// LoggerWithConfig 访问日志中间件，应在其他中间件之前注册，使记录的耗时和状态码包含整个处理过程
+//
+//	file, _ := logger.NewRotatingFile(logger.RotateConfig{
+//		Filename: "logs/access.log",
+//		MaxSize:  100 << 20,
+//		MaxAge:   30 * 24 * time.Hour,
+//	})
+//	r.Use(middleware.LoggerWithConfig(&middleware.LoggerConfig{
+//		Format:        middleware.LogFormatCombined,
+//		Output:        file,
+//		SkipPaths:     []string{"/health"},
+//		SlowThreshold: time.Second,
+//	}))
+func LoggerWithConfig(config *LoggerConfig) HandlerFunc {
+	if config == nil {
+		config = DefaultLoggerConfig()
+	}
+	cfg := *config
+	if cfg.TimeFormat == "" {
+		cfg.TimeFormat = time.RFC3339
+	}
+	if cfg.UserKey == "" {
+		cfg.UserKey = "login"
+	}
+	skip := make(map[string]bool, len(cfg.SkipPaths))
+	for _, path := range cfg.SkipPaths {
+		skip[path] = true
+	}
+
+	var write func(*accessLog)
+	switch cfg.Format {
+	case LogFormatText:
+		write = cfg.writeText
+	case LogFormatJSON:
+		out := newLogWriter(cfg.Output)
+		write = func(l *accessLog) { out.write(func(buf *bytes.Buffer) { l.appendJSON(buf) }) }
+	default:
+		tmpl := cfg.Format
+		switch tmpl {
+		case LogFormatCombined:
+			tmpl = combinedLogTemplate
+		case LogFormatCommon:
+			tmpl = commonLogTemplate
+		}
+		segments := compileLogTemplate(tmpl)
+		out := newLogWriter(cfg.Output)
+		write = func(l *accessLog) {
+			out.write(func(buf *bytes.Buffer) {
+				for _, seg := range segments {
+					seg(buf, l)
+				}
+				buf.WriteByte('\n')
+			})
+		}
+	}
+
+	return func(c *gyarn.Context) {
+		if skip[c.Request.URL.Path] || (cfg.Skip != nil && cfg.Skip(c)) {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+
+		l := &accessLog{
+			config:  &cfg,
+			c:       c,
+			start:   start,
+			latency: time.Since(start),
+			status:  c.Writer.Status(),
+			size:    c.Writer.Size(),
+		}
+		if l.size < 0 {
+			l.size = 0
+		}
+		write(l)
+		if cfg.SlowThreshold > 0 && l.latency > cfg.SlowThreshold {
+			logger.Warn("慢请求",
+				"method", c.Method,
+				"path", c.Request.URL.Path,
+				"route", c.Pattern,
+				"latency", l.latency,
+				"threshold", cfg.SlowThreshold,
+			)
+		}
+	}
+}
+
+// writeText 通过 core/logger 输出结构化日志
+func (cfg *LoggerConfig) writeText(l *accessLog) {
+	args := []any{
+		"status", l.status,
+		"method", l.c.Method,
+		"path", l.c.Request.URL.Path,
+		"ip", l.c.ClientIP(),
+		"size", l.size,
+		"latency", l.latency,
+	}
+	if query := l.c.Request.URL.RawQuery; query != "" {
+		args = append(args, "query", query)
+	}
+	if user := l.user(); user != "" {
+		args = append(args, "user", user)
+	}
+	if id := RequestIDFromContext(l.c); id != "" {
+		args = append(args, "request_id", id)
+	}
+	if err := l.c.LastError(); err != nil {
+		args = append(args, "error", err.Error())
+	}
+	if cfg.Logger != nil {
+		cfg.Logger.Info("请求完成", args...)
+		return
+	}
+	logger.Info("请求完成", args...)
+}
+
+// accessLog 一次请求的日志数据
+type accessLog struct {
+	config  *LoggerConfig
+	c       *gyarn.Context
+	start   time.Time
+	latency time.Duration
+	status  int
+	size    int
+}
+
+// user 返回登录用户 ID
+func (l *accessLog) user() string {
+	if v, ok := l.c.Get(l.config.UserKey); ok {
+		return userID(v)
+	}
+	return ""
+}
+
+// errorString 返回最后记录的错误
+func (l *accessLog) errorString() string {
+	if err := l.c.LastError(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// appendJSON 输出一行 JSON
+func (l *accessLog) appendJSON(buf *bytes.Buffer) {
+	req := l.c.Request
+	entry := struct {
+		Time      string  `json:"time"`
+		Status    int     `json:"status"`
+		Method    string  `json:"method"`
+		Path      string  `json:"path"`
+		Route     string  `json:"route,omitempty"`
+		Query     string  `json:"query,omitempty"`
+		IP        string  `json:"ip"`
+		Size      int     `json:"size"`
+		LatencyMS float64 `json:"latency_ms"`
+		User      string  `json:"user,omitempty"`
+		RequestID string  `json:"request_id,omitempty"`
+		UserAgent string  `json:"user_agent,omitempty"`
+		Referer   string  `json:"referer,omitempty"`
+		Error     string  `json:"error,omitempty"`
+	}{
+		Time:      l.start.Format(l.config.TimeFormat),
+		Status:    l.status,
+		Method:    req.Method,
+		Path:      req.URL.Path,
+		Route:     l.c.Pattern,
+		Query:     req.URL.RawQuery,
+		IP:        l.c.ClientIP(),
+		Size:      l.size,
+		LatencyMS: float64(l.latency.Microseconds()) / 1000,
+		User:      l.user(),
+		RequestID: RequestIDFromContext(l.c),
+		UserAgent: req.UserAgent(),
+		Referer:   req.Referer(),
+		Error:     l.errorString(),
+	}
+	// Encode 会在末尾追加换行
+	if err := json.NewEncoder(buf).Encode(entry); err != nil {
+		buf.WriteString("{}\n")
+	}
+}
+
+// logSegment 模板中的一段，输出常量文本或变量值
+type logSegment func(buf *bytes.Buffer, l *accessLog)
+
+// compileLogTemplate 把模板预先解析为若干段，变量名无效时 panic
+func compileLogTemplate(tmpl string) []logSegment {
+	var segments []logSegment
+	for tmpl != "" {
+		start := strings.Index(tmpl, "${")
+		if start < 0 {
+			segments = append(segments, literalSegment(tmpl))
+			break
+		}
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			panic("middleware: 日志模板缺少 }: " + tmpl[start:])
+		}
+		if start > 0 {
+			segments = append(segments, literalSegment(tmpl[:start]))
+		}
+		segments = append(segments, variableSegment(tmpl[start+2:start+end]))
+		tmpl = tmpl[start+end+1:]
+	}
+	return segments
+}
+
+func literalSegment(s string) logSegment {
+	return func(buf *bytes.Buffer, _ *accessLog) { buf.WriteString(s) }
+}
+
+// variableSegment 返回模板变量的输出函数
+func variableSegment(name string) logSegment {
+	if header, ok := strings.CutPrefix(name, "header:"); ok {
+		return stringSegment(func(l *accessLog) string { return l.c.Request.Header.Get(header) })
+	}
+	switch name {
+	case "time":
+		return func(buf *bytes.Buffer, l *accessLog) { buf.WriteString(l.start.Format(l.config.TimeFormat)) }
+	case "time_clf":
+		return func(buf *bytes.Buffer, l *accessLog) { buf.WriteString(l.start.Format("02/Jan/2006:15:04:05 -0700")) }
+	case "time_unix":
+		return func(buf *bytes.Buffer, l *accessLog) { buf.WriteString(strconv.FormatInt(l.start.Unix(), 10)) }
+	case "ip":
+		return stringSegment(func(l *accessLog) string { return l.c.ClientIP() })
+	case "remote_ip":
+		return stringSegment(func(l *accessLog) string { return l.c.RemoteIP() })
+	case "host":
+		return stringSegment(func(l *accessLog) string { return l.c.Request.Host })
+	case "method":
+		return stringSegment(func(l *accessLog) string { return l.c.Request.Method })
+	case "path":
+		return stringSegment(func(l *accessLog) string { return l.c.Request.URL.Path })
+	case "route":
+		return stringSegment(func(l *accessLog) string { return l.c.Pattern })
+	case "uri":
+		return stringSegment(func(l *accessLog) string { return l.c.Request.RequestURI })
+	case "query":
+		return stringSegment(func(l *accessLog) string { return l.c.Request.URL.RawQuery })
+	case "protocol":
+		return stringSegment(func(l *accessLog) string { return l.c.Request.Proto })
+	case "status":
+		return func(buf *bytes.Buffer, l *accessLog) { buf.WriteString(strconv.Itoa(l.status)) }
+	case "size":
+		return func(buf *bytes.Buffer, l *accessLog) { buf.WriteString(strconv.Itoa(l.size)) }
+	case "latency":
+		return func(buf *bytes.Buffer, l *accessLog) { buf.WriteString(l.latency.String()) }
+	case "latency_ms":
+		return func(buf *bytes.Buffer, l *accessLog) {
+			buf.WriteString(strconv.FormatFloat(float64(l.latency.Microseconds())/1000, 'f', 3, 64))
+		}
+	case "user":
+		return stringSegment((*accessLog).user)
+	case "user_agent":
+		return stringSegment(func(l *accessLog) string { return l.c.Request.UserAgent() })
+	case "referer":
+		return stringSegment(func(l *accessLog) string { return l.c.Request.Referer() })
+	case "error":
+		return stringSegment((*accessLog).errorString)
+	case "request_id":
+		return stringSegment(func(l *accessLog) string { return RequestIDFromContext(l.c) })
+	}
+	panic("middleware: 未知的日志模板变量 " + name)
+}
+
+// stringSegment 输出转义后的字符串，空值输出 "-"
+func stringSegment(value func(*accessLog) string) logSegment {
+	return func(buf *bytes.Buffer, l *accessLog) {
+		s := value(l)
+		if s == "" {
+			buf.WriteByte('-')
+			return
+		}
+		appendEscaped(buf, s)
+	}
+}
+
+// appendEscaped 转义引号、反斜杠和控制字符，避免客户端伪造日志行
+func appendEscaped(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		switch {
+		case b == '"' || b == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(b)
+		case b < 0x20 || b == 0x7f:
+			buf.WriteString(`\x`)
+			buf.WriteByte(hex[b>>4])
+			buf.WriteByte(hex[b&0xf])
+		default:
+			buf.WriteByte(b)
+		}
+	}
+}
+
+// logWriter 串行写入日志行，每行只调用一次 Write，多个请求的日志不会交错
+type logWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+	buf bytes.Buffer
+}
+
+func newLogWriter(out io.Writer) *logWriter {
+	if out == nil {
+		out = os.Stdout
+	}
+	return &logWriter{out: out}
+}
+
+func (w *logWriter) write(fn func(buf *bytes.Buffer)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Reset()
+	fn(&w.buf)
+	if _, err := w.out.Write(w.buf.Bytes()); err != nil {
+		logger.Warn("[Logger] 写入访问日志失败", "error", err)
+	}
+}
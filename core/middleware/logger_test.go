@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+// serveLogged 用指定的访问日志中间件处理一次请求
+func serveLogged(handler HandlerFunc, req *http.Request) {
+	serve(req, func(c *gyarn.Context) {
+		c.Set("login", struct{ ID int }{ID: 42})
+		c.Next()
+	}, handler, func(c *gyarn.Context) { c.String(http.StatusCreated, "hello") })
+}
+
+func TestLoggerCombined(t *testing.T) {
+	var out bytes.Buffer
+	handler := LoggerWithConfig(&LoggerConfig{Format: LogFormatCombined, Output: &out})
+	req := httptest.NewRequest("GET", "/users?page=2", nil)
+	req.Header.Set("User-Agent", `curl/8.0 "x"`)
+	serveLogged(handler, req)
+
+	line := out.String()
+	if !strings.HasPrefix(line, "192.0.2.1 - 42 [") {
+		t.Fatalf("日志行开头不正确: %q", line)
+	}
+	if !strings.HasSuffix(line, `] "GET /users?page=2 HTTP/1.1" 201 5 "-" "curl/8.0 \"x\""`+"\n") {
+		t.Fatalf("日志行结尾不正确: %q", line)
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	var out bytes.Buffer
+	handler := LoggerWithConfig(&LoggerConfig{Format: LogFormatJSON, Output: &out})
+	serveLogged(handler, httptest.NewRequest("POST", "/items?x=1", nil))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("不是合法的 JSON: %v %q", err, out.String())
+	}
+	if entry["status"] != float64(201) || entry["method"] != "POST" || entry["query"] != "x=1" ||
+		entry["user"] != "42" || entry["size"] != float64(5) {
+		t.Fatalf("JSON 字段不正确: %v", entry)
+	}
+}
+
+func TestLoggerTemplateAndSkip(t *testing.T) {
+	var out bytes.Buffer
+	handler := LoggerWithConfig(&LoggerConfig{
+		Format:    "${method} ${path} ${status} ${header:X-Trace} ${referer}",
+		Output:    &out,
+		SkipPaths: []string{"/health"},
+	})
+	serveLogged(handler, httptest.NewRequest("GET", "/health", nil))
+	if out.Len() != 0 {
+		t.Fatalf("跳过的路径不应记录日志: %q", out.String())
+	}
+
+	req := httptest.NewRequest("GET", "/a", nil)
+	req.Header.Set("X-Trace", "t1\nforged")
+	serveLogged(handler, req)
+	if got := out.String(); got != `GET /a 201 t1\x0aforged -`+"\n" {
+		t.Fatalf("模板输出 = %q", got)
+	}
+}
+
+func TestLoggerUnknownVariable(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("未知的模板变量应 panic")
+		}
+	}()
+	LoggerWithConfig(&LoggerConfig{Format: "${nope}"})
+}
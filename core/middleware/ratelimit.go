@@ -0,0 +1,335 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/logger"
+)
+
+// 限流算法
+const (
+	RateLimitTokenBucket   = "token_bucket"   // 令牌桶，允许 BurstSize 以内的突发请求
+	RateLimitSlidingWindow = "sliding_window" // 滑动窗口（按前一窗口计数加权估算），限制平滑且内存占用小
+	RateLimitFixedWindow   = "fixed_window"   // 固定窗口，实现最简单，窗口交界处可能出现两倍的突发
+)
+
+// RateLimitRule 一个限流规则，由 RateLimitConfig 生成后交给 RateLimitStore
+type RateLimitRule struct {
+	Algorithm string
+	// Limit 每个窗口允许的请求数
+	Limit int
+	// Window 窗口长度，令牌桶的补充速率为 Limit/Window
+	Window time.Duration
+	// Burst 令牌桶容量
+	Burst int
+}
+
+// RateLimitResult 一次限流检查的结果
+type RateLimitResult struct {
+	// Allowed 是否允许本次请求
+	Allowed bool
+	// Limit 配额上限，令牌桶为桶容量
+	Limit int
+	// Remaining 剩余配额
+	Remaining int
+	// Reset 配额完全恢复还需要的时间
+	Reset time.Duration
+	// RetryAfter 被拒绝时距离下一次可以请求的时间
+	RetryAfter time.Duration
+}
+
+// RateLimitStore 限流状态存储，内置内存（MemoryRateLimitStore）和 Redis（RedisRateLimitStore）两种实现
+// 多个限流中间件共享同一个存储时，应让 KeyFunc 返回带有不同前缀的 key
+type RateLimitStore interface {
+	// Take 为 key 消耗一次配额
+	Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error)
+}
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	// 每秒允许的请求数，等价于 Limit=RequestsPerSecond、Window=1s
+	RequestsPerSecond int
+	// 突发请求数（令牌桶容量），默认等于 Limit
+	BurstSize int
+	// 是否输出调试日志（需同时开启调试模式）
+	Debug bool
+	// Limit 每个窗口允许的请求数，设置后忽略 RequestsPerSecond
+	Limit int
+	// Window 窗口长度，默认 1 秒
+	Window time.Duration
+	// Algorithm 限流算法，默认 RateLimitTokenBucket
+	Algorithm string
+	// KeyFunc 返回限流的 key，默认 RateLimitByIP；返回空字符串时不限流
+	KeyFunc func(*gyarn.Context) string
+	// Store 限流状态存储，默认为每个中间件单独创建的 MemoryRateLimitStore；多实例部署时使用 RedisRateLimitStore
+	Store RateLimitStore
+	// Skip 返回 true 时跳过限流
+	Skip func(*gyarn.Context) bool
+	// LimitHandler 请求被限流时的处理函数，默认返回 gyarn.ErrTooManyRequests
+	LimitHandler func(*gyarn.Context, RateLimitResult)
+}
+
+// RateLimit 限流中间件，每个客户端 IP 每秒最多 limit 次请求
+func RateLimit(limit int) HandlerFunc {
+	return RateLimitAdvanced(RateLimitConfig{
+		RequestsPerSecond: limit,
+		BurstSize:         limit,
+		Debug:             false,
+	})
+}
+
+// RateLimitAdvanced 高级限流中间件，支持按 IP、用户、API Key、路由限流，以及多种算法和分布式存储
+// 响应中包含 X-RateLimit-Limit、X-RateLimit-Remaining、X-RateLimit-Reset（秒），被限流时包含 Retry-After
+//
+//	r.Use(middleware.RateLimitAdvanced(middleware.RateLimitConfig{
+//		Limit:     600,
+//		Window:    time.Minute,
+//		Algorithm: middleware.RateLimitSlidingWindow,
+//		KeyFunc:   middleware.RateLimitByUser("login"),
+//		Store:     middleware.NewRedisRateLimitStore(rdb, "ratelimit:"),
+//	}))
+func RateLimitAdvanced(config RateLimitConfig) HandlerFunc {
+	rule := config.rule()
+	if config.KeyFunc == nil {
+		config.KeyFunc = RateLimitByIP
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore(0)
+	}
+	if config.LimitHandler == nil {
+		config.LimitHandler = func(c *gyarn.Context, _ RateLimitResult) {
+			c.AbortWithError(gyarn.ErrTooManyRequests)
+		}
+	}
+	if config.Debug {
+		logger.Debug("[RateLimit] 配置",
+			"algorithm", rule.Algorithm, "limit", rule.Limit, "window", rule.Window, "burst", rule.Burst)
+	}
+
+	return func(c *gyarn.Context) {
+		if config.Skip != nil && config.Skip(c) {
+			c.Next()
+			return
+		}
+		key := config.KeyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		result, err := config.Store.Take(c, key, rule)
+		if err != nil {
+			// 存储不可用时放行，避免限流组件故障导致整个服务不可用
+			logger.Warn("[RateLimit] 限流存储出错，已放行", "key", key, "error", err)
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+		if result.Allowed {
+			if config.Debug {
+				logger.Debug("[RateLimit] 请求通过", "key", key, "remaining", result.Remaining, "method", c.Method, "path", c.Path)
+			}
+			c.Next()
+			return
+		}
+		if config.Debug {
+			logger.Debug("[RateLimit] 请求被限流", "key", key, "retry_after", result.RetryAfter, "method", c.Method, "path", c.Path)
+		}
+		header.Set("Retry-After", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+		c.Abort()
+		config.LimitHandler(c, result)
+	}
+}
+
+// rule 按配置生成限流规则并填充默认值
+func (config *RateLimitConfig) rule() RateLimitRule {
+	rule := RateLimitRule{
+		Algorithm: config.Algorithm,
+		Limit:     config.Limit,
+		Window:    config.Window,
+		Burst:     config.BurstSize,
+	}
+	if rule.Limit <= 0 {
+		rule.Limit = config.RequestsPerSecond
+		rule.Window = time.Second
+	}
+	if rule.Limit <= 0 {
+		rule.Limit = 100
+	}
+	if rule.Window <= 0 {
+		rule.Window = time.Second
+	}
+	if rule.Burst <= 0 {
+		rule.Burst = rule.Limit
+	}
+	switch rule.Algorithm {
+	case RateLimitTokenBucket, RateLimitSlidingWindow, RateLimitFixedWindow:
+	case "":
+		rule.Algorithm = RateLimitTokenBucket
+	default:
+		panic("middleware: 未知的限流算法 " + rule.Algorithm)
+	}
+	return rule
+}
+
+// ceilSeconds 向上取整为秒
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// RateLimitByIP 按客户端 IP 限流，IP 的识别方式见 engine.SetTrustedProxies
+func RateLimitByIP(c *gyarn.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimitByRoute 按路由限流，同一路由的所有客户端共享配额
+func RateLimitByRoute(c *gyarn.Context) string {
+	pattern := c.Pattern
+	if pattern == "" {
+		pattern = c.Path
+	}
+	return "route:" + c.Method + " " + pattern
+}
+
+// RateLimitByAPIKey 按请求头中的 API Key 限流，header 为空时使用 X-API-Key；没有 API Key 的请求按 IP 限流
+func RateLimitByAPIKey(header string) func(*gyarn.Context) string {
+	if header == "" {
+		header = "X-API-Key"
+	}
+	return func(c *gyarn.Context) string {
+		if key := c.GetHeader(header); key != "" {
+			return "key:" + key
+		}
+		return RateLimitByIP(c)
+	}
+}
+
+// RateLimitByUser 按登录用户限流，用户取自 c.Get(key)，key 为空时使用 "login"；未登录的请求按 IP 限流
+// 值可以是字符串、整数，或带有 ID、Id、UserID、UserId 字段的结构体（及其指针），如模板项目的 model.LoginUser
+// 需要放在设置登录信息的认证中间件之后
+func RateLimitByUser(key string) func(*gyarn.Context) string {
+	if key == "" {
+		key = "login"
+	}
+	return func(c *gyarn.Context) string {
+		if v, ok := c.Get(key); ok {
+			if id := userID(v); id != "" {
+				return "user:" + id
+			}
+		}
+		return RateLimitByIP(c)
+	}
+}
+
+// userID 从登录信息中取出用户 ID
+func userID(v interface{}) string {
+	switch id := v.(type) {
+	case string:
+		return id
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(id)
+	case fmt.Stringer:
+		return id.String()
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return ""
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return ""
+	}
+	for _, name := range []string{"ID", "Id", "UserID", "UserId"} {
+		if f := rv.FieldByName(name); f.IsValid() && f.CanInterface() && !f.IsZero() {
+			return fmt.Sprint(f.Interface())
+		}
+	}
+	return ""
+}
+
+// tokenBucketResult 按令牌桶剩余令牌数计算结果，tokens 为本次消耗之后的令牌数
+func tokenBucketResult(allowed bool, tokens float64, rule RateLimitRule) RateLimitResult {
+	rate := float64(rule.Limit) / rule.Window.Seconds()
+	result := RateLimitResult{
+		Allowed:   allowed,
+		Limit:     rule.Burst,
+		Remaining: int(tokens),
+		Reset:     time.Duration((float64(rule.Burst) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return result
+}
+
+// refillTokens 按经过的时间补充令牌
+func refillTokens(tokens float64, elapsed time.Duration, rule RateLimitRule) float64 {
+	if elapsed > 0 {
+		tokens += elapsed.Seconds() * float64(rule.Limit) / rule.Window.Seconds()
+	}
+	return math.Min(tokens, float64(rule.Burst))
+}
+
+// windowStart 返回 now 所在窗口的序号和已经过的时间
+func windowStart(now time.Time, window time.Duration) (int64, time.Duration) {
+	n := now.UnixNano()
+	return n / int64(window), time.Duration(n % int64(window))
+}
+
+// fixedWindowResult 按固定窗口计数计算结果，count 为本次计入之后的计数
+func fixedWindowResult(allowed bool, count int, elapsed time.Duration, rule RateLimitRule) RateLimitResult {
+	reset := rule.Window - elapsed
+	result := RateLimitResult{
+		Allowed:   allowed,
+		Limit:     rule.Limit,
+		Remaining: max(rule.Limit-count, 0),
+		Reset:     reset,
+	}
+	if !allowed {
+		result.RetryAfter = reset
+	}
+	return result
+}
+
+// slidingWindowResult 按滑动窗口计数计算结果，curr 为本次计入之后当前窗口的计数，prev 为前一窗口的计数
+// 估算值 = prev × 前一窗口仍在滑动窗口内的比例 + curr
+func slidingWindowResult(allowed bool, curr, prev int, elapsed time.Duration, rule RateLimitRule) RateLimitResult {
+	w := float64(rule.Window)
+	weight := 1 - float64(elapsed)/w
+	estimate := float64(prev)*weight + float64(curr)
+	result := RateLimitResult{
+		Allowed:   allowed,
+		Limit:     rule.Limit,
+		Remaining: max(rule.Limit-int(math.Ceil(estimate)), 0),
+		// 当前窗口的请求完全滑出滑动窗口的时间
+		Reset: 2*rule.Window - elapsed,
+	}
+	if allowed {
+		return result
+	}
+	limit := float64(rule.Limit)
+	var wait float64
+	if float64(curr) >= limit {
+		// 需要等到下一个窗口，此时 curr 变为前一窗口的计数
+		wait = w - float64(elapsed) + w*(1-limit/float64(curr))
+	} else {
+		// 等前一窗口的请求滑出足够多
+		wait = w*(1-(limit-float64(curr))/float64(prev)) - float64(elapsed)
+	}
+	result.RetryAfter = max(time.Duration(math.Ceil(wait)), time.Millisecond)
+	return result
+}
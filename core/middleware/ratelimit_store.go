@@ -0,0 +1,267 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/guyigood/gyweb/core/utils/redisutils"
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultRateLimitKeys 内存限流存储默认最多保存的 key 数量
+const defaultRateLimitKeys = 100000
+
+// rateLimitSweepInterval 清理过期 key 的最小间隔
+const rateLimitSweepInterval = time.Minute
+
+// MemoryRateLimitStore 内存限流存储，适用于单实例部署
+// key 在配额完全恢复后过期，并按最近使用时间淘汰超出 maxKeys 的 key，不需要后台 goroutine
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	maxKeys   int
+	entries   map[string]*list.Element
+	lru       *list.List
+	lastSweep time.Time
+}
+
+// rateLimitEntry 一个 key 的限流状态
+type rateLimitEntry struct {
+	key     string
+	expires time.Time
+	used    time.Time
+	// 令牌桶
+	tokens float64
+	last   time.Time
+	// 窗口算法
+	window int64
+	count  int
+	prev   int
+}
+
+// NewMemoryRateLimitStore 创建内存限流存储，maxKeys 小于等于 0 时最多保存 100000 个 key
+func NewMemoryRateLimitStore(maxKeys int) *MemoryRateLimitStore {
+	if maxKeys <= 0 {
+		maxKeys = defaultRateLimitKeys
+	}
+	return &MemoryRateLimitStore{
+		maxKeys: maxKeys,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Take 为 key 消耗一次配额
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	var e *rateLimitEntry
+	if el, ok := s.entries[key]; ok && now.Before(el.Value.(*rateLimitEntry).expires) {
+		e = el.Value.(*rateLimitEntry)
+		s.lru.MoveToFront(el)
+	} else {
+		if ok {
+			s.lru.Remove(el)
+		}
+		e = &rateLimitEntry{key: key, tokens: float64(rule.Burst), last: now}
+		s.entries[key] = s.lru.PushFront(e)
+		if s.lru.Len() > s.maxKeys {
+			oldest := s.lru.Back()
+			s.lru.Remove(oldest)
+			delete(s.entries, oldest.Value.(*rateLimitEntry).key)
+		}
+	}
+
+	e.used = now
+
+	var result RateLimitResult
+	switch rule.Algorithm {
+	case RateLimitFixedWindow:
+		window, elapsed := windowStart(now, rule.Window)
+		if window != e.window {
+			e.window, e.count = window, 0
+		}
+		allowed := e.count < rule.Limit
+		if allowed {
+			e.count++
+		}
+		result = fixedWindowResult(allowed, e.count, elapsed, rule)
+		e.expires = now.Add(rule.Window - elapsed)
+	case RateLimitSlidingWindow:
+		window, elapsed := windowStart(now, rule.Window)
+		switch window - e.window {
+		case 0:
+		case 1:
+			e.window, e.prev, e.count = window, e.count, 0
+		default:
+			e.window, e.prev, e.count = window, 0, 0
+		}
+		estimate := float64(e.prev)*(1-float64(elapsed)/float64(rule.Window)) + float64(e.count)
+		allowed := estimate < float64(rule.Limit)
+		if allowed {
+			e.count++
+		}
+		result = slidingWindowResult(allowed, e.count, e.prev, elapsed, rule)
+		e.expires = now.Add(2*rule.Window - elapsed)
+	default:
+		e.tokens = refillTokens(e.tokens, now.Sub(e.last), rule)
+		e.last = now
+		allowed := e.tokens >= 1
+		if allowed {
+			e.tokens--
+		}
+		result = tokenBucketResult(allowed, e.tokens, rule)
+		e.expires = now.Add(result.Reset)
+	}
+	return result, nil
+}
+
+// sweep 清理过期的 key；按最近使用时间从旧到新检查，上次清理之后使用过的 key 不会在本次过期，检查到这里即停止
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	lastSweep := s.lastSweep
+	s.lastSweep = now
+	for el := s.lru.Back(); el != nil; {
+		e := el.Value.(*rateLimitEntry)
+		if e.used.After(lastSweep) {
+			break
+		}
+		prev := el.Prev()
+		if !now.Before(e.expires) {
+			s.lru.Remove(el)
+			delete(s.entries, e.key)
+		}
+		el = prev
+	}
+}
+
+// Len 返回当前保存的 key 数量
+func (s *MemoryRateLimitStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// RedisRateLimitStore Redis 限流存储，适用于多实例部署，所有实例共享配额
+// 每次检查通过一个 Lua 脚本原子完成；时间取自各实例的本地时钟，实例之间的时钟需要同步
+type RedisRateLimitStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisRateLimitStore 创建 Redis 限流存储，Redis key 为 prefix + 限流 key
+//
+//	rdb, _ := redisutils.NewRedisClient("127.0.0.1:6379", "", 0)
+//	store := middleware.NewRedisRateLimitStore(rdb, "ratelimit:")
+func NewRedisRateLimitStore(client *redisutils.RedisClient, prefix string) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client.Client, prefix: prefix}
+}
+
+// tokenBucketScript 令牌桶：KEYS[1] 保存令牌数和上次补充时间
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 't', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+	ts = now
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 't', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// fixedWindowScript 固定窗口：KEYS[1] 为当前窗口的计数
+var fixedWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local count = tonumber(redis.call('GET', KEYS[1]) or '0')
+if count >= limit then
+	return {0, count}
+end
+count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return {1, count}
+`)
+
+// slidingWindowScript 滑动窗口：KEYS[1] 为当前窗口的计数，KEYS[2] 为前一窗口的计数
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local weight = tonumber(ARGV[2])
+local curr = tonumber(redis.call('GET', KEYS[1]) or '0')
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+if prev * weight + curr >= limit then
+	return {0, curr, prev}
+end
+curr = redis.call('INCR', KEYS[1])
+if curr == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+return {1, curr, prev}
+`)
+
+// Take 为 key 消耗一次配额
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error) {
+	// 使用 hash tag 保证同一个 key 的多个 Redis key 在集群中位于同一个槽
+	base := s.prefix + "{" + key + "}"
+	now := time.Now()
+
+	switch rule.Algorithm {
+	case RateLimitFixedWindow:
+		window, elapsed := windowStart(now, rule.Window)
+		res, err := fixedWindowScript.Run(ctx, s.client,
+			[]string{base + ":" + strconv.FormatInt(window, 10)},
+			rule.Limit, rule.Window.Milliseconds()).Int64Slice()
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+		return fixedWindowResult(res[0] == 1, int(res[1]), elapsed, rule), nil
+	case RateLimitSlidingWindow:
+		window, elapsed := windowStart(now, rule.Window)
+		weight := 1 - float64(elapsed)/float64(rule.Window)
+		res, err := slidingWindowScript.Run(ctx, s.client,
+			[]string{base + ":" + strconv.FormatInt(window, 10), base + ":" + strconv.FormatInt(window-1, 10)},
+			rule.Limit, strconv.FormatFloat(weight, 'f', -1, 64), (2 * rule.Window).Milliseconds()).Int64Slice()
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+		return slidingWindowResult(res[0] == 1, int(res[1]), int(res[2]), elapsed, rule), nil
+	default:
+		rate := float64(rule.Limit) / float64(rule.Window.Milliseconds())
+		res, err := tokenBucketScript.Run(ctx, s.client, []string{base},
+			strconv.FormatFloat(rate, 'f', -1, 64), rule.Burst, now.UnixMilli()).Slice()
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+		if len(res) != 2 {
+			return RateLimitResult{}, fmt.Errorf("middleware: 限流脚本返回了意外的结果 %v", res)
+		}
+		allowed, _ := res[0].(int64)
+		tokens, err := strconv.ParseFloat(fmt.Sprint(res[1]), 64)
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+		return tokenBucketResult(allowed == 1, tokens, rule), nil
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	for _, algorithm := range []string{RateLimitTokenBucket, RateLimitFixedWindow, RateLimitSlidingWindow} {
+		t.Run(algorithm, func(t *testing.T) {
+			store := NewMemoryRateLimitStore(0)
+			rule := RateLimitRule{Algorithm: algorithm, Limit: 3, Window: time.Hour, Burst: 3}
+			for i := 0; i < 3; i++ {
+				res, _ := store.Take(context.Background(), "a", rule)
+				if !res.Allowed || res.Remaining != 2-i {
+					t.Fatalf("第 %d 次请求: %+v", i+1, res)
+				}
+			}
+			res, _ := store.Take(context.Background(), "a", rule)
+			if res.Allowed || res.Remaining != 0 || res.RetryAfter <= 0 {
+				t.Fatalf("超出配额应被拒绝: %+v", res)
+			}
+			if res, _ := store.Take(context.Background(), "b", rule); !res.Allowed {
+				t.Fatalf("不同的 key 应有独立的配额: %+v", res)
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitStoreEviction(t *testing.T) {
+	store := NewMemoryRateLimitStore(2)
+	rule := RateLimitRule{Algorithm: RateLimitFixedWindow, Limit: 1, Window: time.Hour, Burst: 1}
+	for _, key := range []string{"a", "b", "c"} {
+		store.Take(context.Background(), key, rule)
+	}
+	if store.Len() != 2 {
+		t.Fatalf("Len() = %d，期望 2", store.Len())
+	}
+	// a 最久未使用，已被淘汰，重新获得配额
+	if res, _ := store.Take(context.Background(), "a", rule); !res.Allowed {
+		t.Fatalf("被淘汰的 key 应重新计数: %+v", res)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	handler := RateLimitAdvanced(RateLimitConfig{Limit: 2, Window: time.Minute, Algorithm: RateLimitFixedWindow})
+	do := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		return serve(req, handler, respondOK)
+	}
+
+	do("10.0.0.1")
+	w := do("10.0.0.1")
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("第二次请求: %d %v", w.Code, w.Header())
+	}
+	w = do("10.0.0.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("第三次请求应返回 429 和 Retry-After: %d %v", w.Code, w.Header())
+	}
+	if w = do("10.0.0.2"); w.Code != http.StatusOK {
+		t.Fatalf("其他 IP 不应受影响: %d", w.Code)
+	}
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	type loginUser struct {
+		ID       int
+		Username string
+	}
+	c := gyarn.NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	keyFunc := RateLimitByUser("")
+	if got := keyFunc(c); got != "ip:192.0.2.1" {
+		t.Errorf("未登录时应按 IP 限流，实际 %q", got)
+	}
+	c.Set("login", loginUser{ID: 7, Username: "admin"})
+	if got := keyFunc(c); got != "user:7" {
+		t.Errorf("RateLimitByUser = %q，期望 user:7", got)
+	}
+	c.Set("login", &loginUser{ID: 8})
+	if got := keyFunc(c); got != "user:8" {
+		t.Errorf("RateLimitByUser = %q，期望 user:8", got)
+	}
+}
@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/logger"
+)
+
+// redactedValue 脱敏后的值
+const redactedValue = "******"
+
+// PanicInfo 一次 panic 的信息，传给 RecoveryConfig.Handler 和 Notifier
+type PanicInfo struct {
+	// Err recover() 得到的值
+	Err interface{}
+	// Stack 堆栈，DisableStack 时为空
+	Stack []byte
+	// Request 脱敏后的请求行和请求头，不含请求体
+	Request string
+	Method  string
+	Path    string
+	IP      string
+	Time    time.Time
+	// RequestID 请求 ID，需要注册 RequestID 中间件
+	RequestID string
+	// BrokenPipe 客户端已断开连接，此时无法再输出响应
+	BrokenPipe bool
+}
+
+// RecoveryConfig 恢复中间件配置
+type RecoveryConfig struct {
+	// DisableStack 不记录堆栈
+	DisableStack bool
+	// DisableRequestDump 日志中不记录请求头
+	DisableRequestDump bool
+	// RedactHeaders 记录请求时需要脱敏的请求头，不区分大小写
+	RedactHeaders []string
+	// RedactQuery 记录请求时需要脱敏的查询参数，不区分大小写
+	RedactQuery []string
+	// Handler 输出错误响应，默认通过 c.AbortWithError 返回 gyarn.ErrInternalServer，由错误渲染器统一输出；
+	// 响应头已经发送或客户端已断开时不会调用
+	Handler func(c *gyarn.Context, info *PanicInfo)
+	// Notifier 发送告警，在单独的 goroutine 中调用，客户端断开引起的 panic 不会通知；
+	// 可以使用 dingtalk.PanicNotifier 发送到钉钉群
+	Notifier func(info *PanicInfo)
+}
+
+// DefaultRecoveryConfig 返回默认配置：记录堆栈和脱敏后的请求头，以统一错误格式返回 500
+func DefaultRecoveryConfig() *RecoveryConfig {
+	return &RecoveryConfig{
+		RedactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "Token", "X-API-Key", "X-Auth-Token"},
+		RedactQuery:   []string{"token", "access_token", "password", "secret", "api_key", "sign"},
+	}
+}
+
+// Recovery 恢复中间件，使用默认配置
+func Recovery() HandlerFunc {
+	return RecoveryWithConfig(DefaultRecoveryConfig())
+}
+
+// RecoveryWithConfig 恢复中间件，捕获处理链中的 panic，记录堆栈和请求信息后返回 500
+// 应注册在 Logger 之后、其他中间件之前，使访问日志能记录到 500 状态码
+//
+//	robot := dingtalk.NewDingTalk(&dingtalk.DingTalkConfig{RobotToken: "...", RobotSecret: "..."})
+//	r.Use(middleware.RecoveryWithConfig(&middleware.RecoveryConfig{
+//		Handler: func(c *gyarn.Context, info *middleware.PanicInfo) {
+//			c.JSON(http.StatusInternalServerError, gyarn.Response{Code: 50000, Message: "系统繁忙，请稍后再试"})
+//		},
+//		Notifier: dingtalk.PanicNotifier(robot, "订单服务异常"),
+//	}))
+func RecoveryWithConfig(config *RecoveryConfig) HandlerFunc {
+	if config == nil {
+		config = DefaultRecoveryConfig()
+	}
+	cfg := *config
+	redactHeaders := make(map[string]bool, len(cfg.RedactHeaders))
+	for _, h := range cfg.RedactHeaders {
+		redactHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+	redactQuery := make(map[string]bool, len(cfg.RedactQuery))
+	for _, q := range cfg.RedactQuery {
+		redactQuery[strings.ToLower(q)] = true
+	}
+	if cfg.Handler == nil {
+		cfg.Handler = func(c *gyarn.Context, info *PanicInfo) {
+			c.AbortWithError(gyarn.ErrInternalServer.WithError(fmt.Errorf("panic: %v", info.Err)))
+		}
+	}
+
+	return func(c *gyarn.Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				// 约定用于中止响应，交给 net/http 处理
+				panic(err)
+			}
+
+			info := &PanicInfo{
+				Err:        err,
+				Method:     c.Request.Method,
+				Path:       c.Request.URL.Path,
+				IP:         c.ClientIP(),
+				Time:       time.Now(),
+				RequestID:  RequestIDFromContext(c),
+				BrokenPipe: isBrokenPipe(err),
+			}
+			if !cfg.DisableRequestDump {
+				info.Request = dumpRequest(c.Request, redactHeaders, redactQuery)
+			}
+
+			if info.BrokenPipe {
+				// 客户端已断开，堆栈没有参考价值，也无法再输出响应
+				logger.Warn("客户端连接已断开", "error", err, "method", info.Method, "path", info.Path, "ip", info.IP,
+					"request_id", info.RequestID)
+				if e, ok := err.(error); ok {
+					c.AddError(e)
+				}
+				c.Abort()
+				return
+			}
+
+			if !cfg.DisableStack {
+				info.Stack = debug.Stack()
+			}
+			logger.Error("panic recovered",
+				"error", err,
+				"method", info.Method,
+				"path", info.Path,
+				"ip", info.IP,
+				"request_id", info.RequestID,
+				"request", info.Request,
+				"stack", string(info.Stack),
+			)
+			if cfg.Notifier != nil {
+				go notifyPanic(cfg.Notifier, info)
+			}
+
+			if c.Writer.Written() {
+				// 响应头已经发送，只能中止后续处理
+				c.AddError(fmt.Errorf("panic: %v", err))
+				c.Abort()
+				return
+			}
+			c.Abort()
+			cfg.Handler(c, info)
+		}()
+		c.Next()
+	}
+}
+
+// notifyPanic 调用告警函数，告警函数本身的 panic 不会影响服务
+func notifyPanic(notify func(*PanicInfo), info *PanicInfo) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Error("panic 告警发送失败", "error", err)
+		}
+	}()
+	notify(info)
+}
+
+// isBrokenPipe 判断 panic 是否由客户端断开连接引起
+func isBrokenPipe(v interface{}) bool {
+	err, ok := v.(error)
+	if !ok {
+		return false
+	}
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
+
+// dumpRequest 输出脱敏后的请求行和请求头
+func dumpRequest(req *http.Request, redactHeaders, redactQuery map[string]bool) string {
+	r := *req
+	r.Header = req.Header.Clone()
+	for name := range r.Header {
+		if redactHeaders[name] {
+			r.Header[name] = []string{redactedValue}
+		}
+	}
+	if r.URL != nil && r.URL.RawQuery != "" {
+		u := *r.URL
+		if query, err := url.ParseQuery(u.RawQuery); err == nil {
+			redacted := false
+			for name := range query {
+				if redactQuery[strings.ToLower(name)] {
+					query[name] = []string{redactedValue}
+					redacted = true
+				}
+			}
+			if redacted {
+				u.RawQuery = query.Encode()
+				r.URL = &u
+				r.RequestURI = u.RequestURI()
+			}
+		}
+	}
+	dump, err := httputil.DumpRequest(&r, false)
+	if err != nil {
+		return r.Method + " " + r.URL.Path
+	}
+	return strings.TrimSpace(string(dump))
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+func TestRecoveryDefault(t *testing.T) {
+	w := serve(httptest.NewRequest("GET", "/", nil), Recovery(), func(c *gyarn.Context) {
+		panic("boom")
+	})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("状态码 = %d，期望 500", w.Code)
+	}
+	var resp gyarn.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code != http.StatusInternalServerError {
+		t.Fatalf("应返回统一的错误格式: %v %q", err, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "boom") {
+		t.Fatalf("panic 信息不应返回给客户端: %q", w.Body.String())
+	}
+}
+
+func TestRecoveryHandlerAndNotifier(t *testing.T) {
+	notified := make(chan *PanicInfo, 1)
+	handler := RecoveryWithConfig(&RecoveryConfig{
+		RedactHeaders: []string{"Authorization"},
+		RedactQuery:   []string{"token"},
+		Handler: func(c *gyarn.Context, info *PanicInfo) {
+			c.JSON(http.StatusServiceUnavailable, gyarn.Response{Code: 50301, Message: "系统繁忙"})
+		},
+		Notifier: func(info *PanicInfo) { notified <- info },
+	})
+	req := httptest.NewRequest("GET", "/orders?token=abc&id=1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := serve(req, handler, func(c *gyarn.Context) { panic(fmt.Errorf("nil order")) })
+
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "50301") {
+		t.Fatalf("应使用自定义处理函数: %d %q", w.Code, w.Body.String())
+	}
+	info := <-notified
+	if len(info.Stack) == 0 || info.Path != "/orders" {
+		t.Fatalf("告警信息不完整: %+v", info)
+	}
+	if strings.Contains(info.Request, "secret") || strings.Contains(info.Request, "abc") ||
+		!strings.Contains(info.Request, "id=1") {
+		t.Fatalf("请求信息未正确脱敏: %q", info.Request)
+	}
+}
+
+func TestRecoveryAfterWritten(t *testing.T) {
+	w := serve(httptest.NewRequest("GET", "/", nil), Recovery(), func(c *gyarn.Context) {
+		c.String(http.StatusOK, "partial")
+		c.Writer.Flush()
+		panic("boom")
+	})
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Fatalf("响应头已发送时不应再输出错误响应: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRecoveryBrokenPipe(t *testing.T) {
+	called := false
+	handler := RecoveryWithConfig(&RecoveryConfig{
+		Handler:  func(c *gyarn.Context, info *PanicInfo) { called = true },
+		Notifier: func(info *PanicInfo) { called = true },
+	})
+	serve(httptest.NewRequest("GET", "/", nil), handler, func(c *gyarn.Context) {
+		panic(fmt.Errorf("write tcp: %w", syscall.EPIPE))
+	})
+	if called {
+		t.Fatal("客户端断开时不应调用 Handler 和 Notifier")
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/utils/common"
+)
+
+// HeaderRequestID 默认的请求 ID 请求头和响应头
+const HeaderRequestID = "X-Request-ID"
+
+// RequestIDKey 请求 ID 在 Context 中的 key，可通过 c.GetString(middleware.RequestIDKey) 读取
+const RequestIDKey = "request_id"
+
+// maxRequestIDLength 接受的客户端请求 ID 最大长度
+const maxRequestIDLength = 128
+
+// requestIDContextKey 请求 ID 在 Request.Context() 中的 key
+type requestIDContextKey struct{}
+
+// RequestIDConfig 请求 ID 中间件配置
+type RequestIDConfig struct {
+	// Header 读取和返回请求 ID 的头，默认 X-Request-ID
+	Header string
+	// Generator 生成请求 ID，默认 UUIDRequestID，也可使用 SnowflakeRequestID
+	Generator func() string
+	// IgnoreIncoming 忽略客户端传入的请求 ID，总是重新生成；服务直接面向公网时可以开启
+	IgnoreIncoming bool
+}
+
+// DefaultRequestIDConfig 返回默认配置：沿用上游传入的 X-Request-ID，没有时生成 UUID
+func DefaultRequestIDConfig() *RequestIDConfig {
+	return &RequestIDConfig{
+		Header:    HeaderRequestID,
+		Generator: UUIDRequestID,
+	}
+}
+
+// RequestID 请求 ID 中间件，使用默认配置
+func RequestID() HandlerFunc {
+	return RequestIDWithConfig(DefaultRequestIDConfig())
+}
+
+// RequestIDWithConfig 请求 ID 中间件，为每个请求分配 ID，保存到 c.Keys 和 Request.Context() 并通过响应头返回
+// 访问日志、错误恢复和 SQL 调试日志会自动带上请求 ID；应注册在最前面，使后续中间件都能取到
+//
+//	r.Use(middleware.RequestIDWithConfig(&middleware.RequestIDConfig{Generator: middleware.SnowflakeRequestID}))
+//	r.Use(middleware.Logger())
+func RequestIDWithConfig(config *RequestIDConfig) HandlerFunc {
+	if config == nil {
+		config = DefaultRequestIDConfig()
+	}
+	header := config.Header
+	if header == "" {
+		header = HeaderRequestID
+	}
+	generate := config.Generator
+	if generate == nil {
+		generate = UUIDRequestID
+	}
+
+	return func(c *gyarn.Context) {
+		id := ""
+		if !config.IgnoreIncoming {
+			id = c.GetHeader(header)
+			if !validRequestID(id) {
+				id = ""
+			}
+		}
+		if id == "" {
+			id = generate()
+		}
+		c.Set(RequestIDKey, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDContextKey{}, id))
+		c.Writer.Header().Set(header, id)
+		c.Next()
+	}
+}
+
+// RequestIDFromContext 返回 ctx 中的请求 ID，没有时返回空字符串
+// ctx 可以是 *gyarn.Context、它的副本、c.Request.Context() 或由它们派生的 context，
+// 调用第三方接口时可以通过请求头转发，便于关联日志：
+//
+//	req.Header.Set(middleware.HeaderRequestID, middleware.RequestIDFromContext(c))
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// UUIDRequestID 生成 UUID 格式的请求 ID
+func UUIDRequestID() string {
+	return common.GetUUID()
+}
+
+// SnowflakeRequestID 生成雪花算法请求 ID，与 orm 的 SnowflakeID 共享同一个节点
+func SnowflakeRequestID() string {
+	return strconv.FormatInt(common.SnowflakeID(), 10)
+}
+
+// validRequestID 只接受长度有限的可见 ASCII 字符，避免把任意内容写入日志和响应头
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
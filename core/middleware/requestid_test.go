@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+func TestRequestID(t *testing.T) {
+	var got string
+	do := func(handler HandlerFunc, incoming string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		if incoming != "" {
+			req.Header.Set(HeaderRequestID, incoming)
+		}
+		return serve(req, handler, func(c *gyarn.Context) {
+			got = RequestIDFromContext(c.Request.Context())
+			if c.GetString(RequestIDKey) != got || RequestIDFromContext(c.Copy()) != got {
+				t.Errorf("c.Keys、副本和 Request.Context() 中的请求 ID 不一致")
+			}
+		})
+	}
+
+	w := do(RequestID(), "")
+	if len(got) != 36 || w.Header().Get(HeaderRequestID) != got {
+		t.Fatalf("应生成 UUID 并通过响应头返回: %q %q", got, w.Header().Get(HeaderRequestID))
+	}
+	if do(RequestID(), "upstream-1"); got != "upstream-1" {
+		t.Fatalf("应沿用上游的请求 ID，实际 %q", got)
+	}
+	if do(RequestID(), "bad id\x00"); got == "bad id\x00" || got == "" {
+		t.Fatalf("应拒绝非法的请求 ID，实际 %q", got)
+	}
+
+	snowflake := RequestIDWithConfig(&RequestIDConfig{Generator: SnowflakeRequestID, IgnoreIncoming: true})
+	do(snowflake, "upstream-1")
+	first := got
+	do(snowflake, "")
+	if first == "upstream-1" || first == got || strings.Trim(first, "0123456789") != "" {
+		t.Fatalf("应生成不重复的雪花 ID: %q %q", first, got)
+	}
+}
+
+func TestLoggerIncludesRequestID(t *testing.T) {
+	var out bytes.Buffer
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderRequestID, "abc-123")
+	serve(req,
+		RequestID(),
+		LoggerWithConfig(&LoggerConfig{Format: "${request_id} ${status}", Output: &out}),
+		func(c *gyarn.Context) { c.Status(http.StatusNoContent) },
+	)
+	if out.String() != "abc-123 204\n" {
+		t.Fatalf("访问日志 = %q", out.String())
+	}
+}
@@ -0,0 +1,367 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/keyring"
+	"github.com/guyigood/gyweb/core/logger"
+)
+
+func init() {
+	// 闪存消息以 []interface{} 保存在会话中
+	gob.Register([]interface{}(nil))
+	gob.Register(map[string]interface{}(nil))
+}
+
+// SessionKey 会话在上下文中保存的 key
+const SessionKey = "gyweb.session"
+
+// flashKey 闪存消息在会话中保存的 key 前缀
+const flashKey = "_flash"
+
+// maxCookieSize 浏览器允许的单个 cookie 最大字节数
+const maxCookieSize = 4096
+
+// ErrSessionTooLarge cookie 会话编码后超过浏览器的 cookie 大小限制
+var ErrSessionTooLarge = errors.New("middleware: 会话数据超过 cookie 大小限制，请改用服务端存储")
+
+// SessionOptions 会话 cookie 的属性
+type SessionOptions struct {
+	Path   string
+	Domain string
+	// MaxAge 有效期（秒），小于 0 表示删除会话；0 表示浏览器关闭后失效，此时服务端存储最多保留 24 小时
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// DefaultSessionOptions 返回默认的会话属性：有效期 7 天，HttpOnly，SameSite=Lax
+func DefaultSessionOptions() *SessionOptions {
+	return &SessionOptions{
+		Path:     "/",
+		MaxAge:   7 * 24 * 3600,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// ttl 服务端保存会话的时长
+func (o *SessionOptions) ttl() time.Duration {
+	if o.MaxAge > 0 {
+		return time.Duration(o.MaxAge) * time.Second
+	}
+	return 24 * time.Hour
+}
+
+// cookie 按会话属性生成 cookie
+func (o *SessionOptions) cookie(name, value string) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		MaxAge:   o.MaxAge,
+		Secure:   o.Secure,
+		HttpOnly: o.HttpOnly,
+		SameSite: o.SameSite,
+	}
+	if o.MaxAge > 0 {
+		cookie.Expires = time.Now().Add(time.Duration(o.MaxAge) * time.Second)
+	} else if o.MaxAge < 0 {
+		cookie.Value = ""
+		cookie.Expires = time.Unix(0, 0)
+	}
+	return cookie
+}
+
+// SessionStore 会话存储，内置 cookie（CookieSessionStore）、内存（MemorySessionStore）和 Redis（RedisSessionStore）三种实现
+type SessionStore interface {
+	// Load 读取会话，不存在时返回新的空会话；cookie 被篡改、已过期或存储不可用时返回新的空会话和错误
+	Load(c *gyarn.Context, name string) (*Session, error)
+	// Save 保存会话并写出 cookie，Options.MaxAge 小于 0 时删除会话
+	Save(c *gyarn.Context, session *Session) error
+}
+
+// Session 一个会话，值需要能被 encoding/gob 编码，自定义类型需要先调用 gob.Register
+type Session struct {
+	// Name cookie 名称
+	Name string
+	// ID 服务端存储中的会话 ID，cookie 会话为空
+	ID string
+	// Values 会话数据
+	Values map[string]interface{}
+	// Options 本会话的 cookie 属性，修改后保存时生效
+	Options *SessionOptions
+	// IsNew 是否为本次请求新建的会话
+	IsNew bool
+
+	store    SessionStore
+	c        *gyarn.Context
+	modified bool
+	// oldID Regenerate 之前的会话 ID，保存时删除
+	oldID string
+}
+
+// NewSession 创建空会话，供 SessionStore 的实现使用
+func NewSession(c *gyarn.Context, store SessionStore, name string, options *SessionOptions) *Session {
+	opts := *options
+	return &Session{
+		Name:    name,
+		Values:  make(map[string]interface{}),
+		Options: &opts,
+		IsNew:   true,
+		store:   store,
+		c:       c,
+	}
+}
+
+// Get 获取值
+func (s *Session) Get(key string) interface{} {
+	return s.Values[key]
+}
+
+// Set 设置值
+func (s *Session) Set(key string, value interface{}) {
+	s.Values[key] = value
+	s.modified = true
+}
+
+// Delete 删除值
+func (s *Session) Delete(key string) {
+	delete(s.Values, key)
+	s.modified = true
+}
+
+// Clear 清空所有值
+func (s *Session) Clear() {
+	clear(s.Values)
+	s.modified = true
+}
+
+// AddFlash 添加闪存消息，消息在下一次通过 Flashes 读取后删除，常用于重定向后显示提示
+// category 为空时使用默认分类
+func (s *Session) AddFlash(value interface{}, category ...string) {
+	key := flashCategory(category)
+	flashes, _ := s.Values[key].([]interface{})
+	s.Values[key] = append(flashes, value)
+	s.modified = true
+}
+
+// Flashes 读取并删除闪存消息
+func (s *Session) Flashes(category ...string) []interface{} {
+	key := flashCategory(category)
+	flashes, ok := s.Values[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	delete(s.Values, key)
+	s.modified = true
+	return flashes
+}
+
+func flashCategory(category []string) string {
+	if len(category) > 0 && category[0] != "" {
+		return flashKey + "_" + category[0]
+	}
+	return flashKey
+}
+
+// Regenerate 更换会话 ID 并在保存时删除旧会话，登录等权限变化时调用以防止会话固定攻击；cookie 会话无需调用
+func (s *Session) Regenerate() {
+	if s.ID != "" && s.oldID == "" {
+		s.oldID = s.ID
+	}
+	s.ID = ""
+	s.modified = true
+}
+
+// Destroy 清空会话并在保存时删除
+func (s *Session) Destroy() {
+	clear(s.Values)
+	s.Options.MaxAge = -1
+	s.modified = true
+}
+
+// Save 立即保存会话；通过 GetSession 获取的会话修改后会在写出响应前自动保存，只有写出响应之后修改才需要手动调用
+func (s *Session) Save() error {
+	if err := s.store.Save(s.c, s); err != nil {
+		return err
+	}
+	s.modified = false
+	s.IsNew = false
+	return nil
+}
+
+// sessionHolder 延迟加载的会话，保存在上下文中
+type sessionHolder struct {
+	name    string
+	store   SessionStore
+	session *Session
+}
+
+// defaultSessionStore 未使用 Sessions 中间件时 GetSession、SetSession 使用的存储
+var defaultSessionStore SessionStore
+
+// SetSessionStore 设置默认会话存储，供未使用 Sessions 中间件的 GetSession、SetSession、UseSession 使用
+func SetSessionStore(store SessionStore) {
+	defaultSessionStore = store
+}
+
+// Sessions 会话中间件，使用 name 作为 cookie 名称
+// 会话在首次调用 GetSession 时加载，修改过的会话在写出响应头之前自动保存
+//
+//	ring, _ := keyring.New([]byte(os.Getenv("SESSION_KEY")))
+//	r.Use(middleware.Sessions("sid", middleware.NewCookieSessionStore(ring, nil)))
+//	// 或保存在 Redis 中：middleware.NewRedisSessionStore(rdb, "session:", nil)
+//
+//	s := middleware.GetSession(c)
+//	s.Set("user_id", 1)
+//	s.AddFlash("保存成功")
+func Sessions(name string, store SessionStore) gyarn.HandlerFunc {
+	return func(c *gyarn.Context) {
+		attachSession(c, name, store)
+		c.Next()
+	}
+}
+
+// attachSession 在上下文中登记会话，并在写出响应头之前保存修改
+func attachSession(c *gyarn.Context, name string, store SessionStore) *sessionHolder {
+	holder := &sessionHolder{name: name, store: store}
+	c.Set(SessionKey, holder)
+	c.Writer.Before(func(gyarn.ResponseWriter) {
+		if s := holder.session; s != nil && s.modified {
+			if err := s.Save(); err != nil {
+				logger.Error("保存会话失败", "name", s.Name, "error", err, "path", c.Request.URL.Path)
+			}
+		}
+	})
+	return holder
+}
+
+// GetSession 获取当前请求的会话，会话无效时返回新的空会话
+// 未使用 Sessions 中间件时使用 SetSessionStore、InitSessionStore 设置的默认存储，cookie 名称为 session
+func GetSession(c *gyarn.Context) *Session {
+	var holder *sessionHolder
+	if v, ok := c.Get(SessionKey); ok {
+		holder = v.(*sessionHolder)
+	} else {
+		if defaultSessionStore == nil {
+			panic("middleware: 未设置会话存储，请使用 Sessions 中间件或先调用 SetSessionStore、InitSessionStore")
+		}
+		holder = attachSession(c, "session", defaultSessionStore)
+	}
+	if holder.session == nil {
+		s, err := holder.store.Load(c, holder.name)
+		if err != nil {
+			logger.Debug("会话无效，已创建新会话", "name", holder.name, "error", err)
+		}
+		holder.session = s
+	}
+	return holder.session
+}
+
+// CookieSessionStore 把会话加密后保存在 cookie 中，无需服务端存储，数据量受 cookie 大小限制（约 4KB）
+type CookieSessionStore struct {
+	ring *keyring.Ring
+	// Options 新会话的 cookie 属性
+	Options *SessionOptions
+}
+
+// NewCookieSessionStore 创建 cookie 会话存储，options 为 nil 时使用 DefaultSessionOptions
+func NewCookieSessionStore(ring *keyring.Ring, options *SessionOptions) *CookieSessionStore {
+	if options == nil {
+		options = DefaultSessionOptions()
+	}
+	return &CookieSessionStore{ring: ring, Options: options}
+}
+
+// Load 读取会话
+func (s *CookieSessionStore) Load(c *gyarn.Context, name string) (*Session, error) {
+	session := NewSession(c, s, name, s.Options)
+	value, err := c.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	data, err := s.ring.Decrypt(name, value)
+	if err != nil {
+		return session, err
+	}
+	if err := decodeSession(data, session); err != nil {
+		return session, err
+	}
+	session.IsNew = false
+	return session, nil
+}
+
+// Save 保存会话
+func (s *CookieSessionStore) Save(c *gyarn.Context, session *Session) error {
+	if session.Options.MaxAge < 0 {
+		c.SetCookieWith(session.Options.cookie(session.Name, ""))
+		return nil
+	}
+	data, err := encodeSession(session)
+	if err != nil {
+		return err
+	}
+	value, err := s.ring.Encrypt(session.Name, data)
+	if err != nil {
+		return err
+	}
+	cookie := session.Options.cookie(session.Name, value)
+	if len(cookie.String()) > maxCookieSize {
+		return ErrSessionTooLarge
+	}
+	c.SetCookieWith(cookie)
+	return nil
+}
+
+// sessionEnvelope 编码后的会话，Expires 用于在服务端校验 cookie 会话的有效期
+type sessionEnvelope struct {
+	Values  map[string]interface{}
+	Expires int64
+}
+
+// encodeSession 使用 gob 编码会话数据
+func encodeSession(session *Session) ([]byte, error) {
+	env := sessionEnvelope{Values: session.Values}
+	if session.Options.MaxAge > 0 {
+		env.Expires = time.Now().Add(time.Duration(session.Options.MaxAge) * time.Second).Unix()
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(env); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeSession 解码会话数据，已过期时返回错误
+func decodeSession(data []byte, session *Session) error {
+	var env sessionEnvelope
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&env); err != nil {
+		return err
+	}
+	if env.Expires > 0 && time.Now().Unix() > env.Expires {
+		return keyring.ErrExpired
+	}
+	if env.Values != nil {
+		session.Values = env.Values
+	}
+	return nil
+}
+
+// newSessionID 生成随机会话 ID
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
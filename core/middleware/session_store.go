@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/utils/redisutils"
+	"github.com/redis/go-redis/v9"
+)
+
+// sessionBackend 服务端会话数据的存储
+type sessionBackend interface {
+	// get 读取会话数据，不存在时返回 nil, nil
+	get(ctx context.Context, id string) ([]byte, error)
+	set(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	del(ctx context.Context, id string) error
+}
+
+// serverSessionStore 会话数据保存在服务端，cookie 中只保存随机生成的会话 ID
+type serverSessionStore struct {
+	backend sessionBackend
+	// Options 新会话的 cookie 属性
+	Options *SessionOptions
+}
+
+// load 读取会话
+func (s *serverSessionStore) load(store SessionStore, c *gyarn.Context, name string) (*Session, error) {
+	session := NewSession(c, store, name, s.Options)
+	id, err := c.Cookie(name)
+	if err != nil || id == "" {
+		return session, nil
+	}
+	data, err := s.backend.get(c, id)
+	if err != nil || data == nil {
+		return session, err
+	}
+	if err := decodeSession(data, session); err != nil {
+		return session, err
+	}
+	session.ID = id
+	session.IsNew = false
+	return session, nil
+}
+
+// save 保存会话，删除时同时清除 cookie
+func (s *serverSessionStore) save(c *gyarn.Context, session *Session) error {
+	if session.oldID != "" {
+		if err := s.backend.del(c, session.oldID); err != nil {
+			return err
+		}
+		session.oldID = ""
+	}
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.backend.del(c, session.ID); err != nil {
+				return err
+			}
+		}
+		c.SetCookieWith(session.Options.cookie(session.Name, ""))
+		return nil
+	}
+	if session.ID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	}
+	data, err := encodeSession(session)
+	if err != nil {
+		return err
+	}
+	if err := s.backend.set(c, session.ID, data, session.Options.ttl()); err != nil {
+		return err
+	}
+	c.SetCookieWith(session.Options.cookie(session.Name, session.ID))
+	return nil
+}
+
+// MemorySessionStore 内存会话存储，适用于单实例部署和测试，进程重启后会话丢失
+type MemorySessionStore struct {
+	serverSessionStore
+	backend *memorySessionBackend
+}
+
+// NewMemorySessionStore 创建内存会话存储，options 为 nil 时使用 DefaultSessionOptions
+func NewMemorySessionStore(options *SessionOptions) *MemorySessionStore {
+	if options == nil {
+		options = DefaultSessionOptions()
+	}
+	backend := &memorySessionBackend{sessions: make(map[string]memorySession)}
+	return &MemorySessionStore{
+		serverSessionStore: serverSessionStore{backend: backend, Options: options},
+		backend:            backend,
+	}
+}
+
+// Load 读取会话
+func (s *MemorySessionStore) Load(c *gyarn.Context, name string) (*Session, error) {
+	return s.load(s, c, name)
+}
+
+// Save 保存会话
+func (s *MemorySessionStore) Save(c *gyarn.Context, session *Session) error {
+	return s.save(c, session)
+}
+
+// Len 返回未过期的会话数量
+func (s *MemorySessionStore) Len() int {
+	return s.backend.len()
+}
+
+// memorySweepInterval 清理过期会话的最小间隔
+const memorySweepInterval = time.Minute
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+type memorySessionBackend struct {
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+}
+
+func (b *memorySessionBackend) get(ctx context.Context, id string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ms, ok := b.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(ms.expires) {
+		delete(b.sessions, id)
+		return nil, nil
+	}
+	return ms.data, nil
+}
+
+func (b *memorySessionBackend) set(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.sessions[id] = memorySession{data: data, expires: now.Add(ttl)}
+	// 写入时顺带清理过期会话，避免长期运行时内存持续增长
+	if now.Sub(b.lastSweep) > memorySweepInterval {
+		b.lastSweep = now
+		for key, ms := range b.sessions {
+			if now.After(ms.expires) {
+				delete(b.sessions, key)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *memorySessionBackend) del(ctx context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.sessions, id)
+	return nil
+}
+
+func (b *memorySessionBackend) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	n := 0
+	for _, ms := range b.sessions {
+		if !now.After(ms.expires) {
+			n++
+		}
+	}
+	return n
+}
+
+// RedisSessionStore Redis 会话存储，适用于多实例部署，会话按有效期自动过期
+type RedisSessionStore struct {
+	serverSessionStore
+}
+
+// NewRedisSessionStore 创建 Redis 会话存储，key 为 prefix + 会话 ID；options 为 nil 时使用 DefaultSessionOptions
+//
+//	rdb, _ := redisutils.NewRedisClient("127.0.0.1:6379", "", 0)
+//	store := middleware.NewRedisSessionStore(rdb, "session:", nil)
+func NewRedisSessionStore(client *redisutils.RedisClient, prefix string, options *SessionOptions) *RedisSessionStore {
+	if options == nil {
+		options = DefaultSessionOptions()
+	}
+	return &RedisSessionStore{
+		serverSessionStore: serverSessionStore{
+			backend: &redisSessionBackend{client: client.Client, prefix: prefix},
+			Options: options,
+		},
+	}
+}
+
+// Load 读取会话
+func (s *RedisSessionStore) Load(c *gyarn.Context, name string) (*Session, error) {
+	return s.load(s, c, name)
+}
+
+// Save 保存会话
+func (s *RedisSessionStore) Save(c *gyarn.Context, session *Session) error {
+	return s.save(c, session)
+}
+
+type redisSessionBackend struct {
+	client *redis.Client
+	prefix string
+}
+
+func (b *redisSessionBackend) get(ctx context.Context, id string) ([]byte, error) {
+	data, err := b.client.Get(ctx, b.prefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (b *redisSessionBackend) set(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return b.client.Set(ctx, b.prefix+id, data, ttl).Err()
+}
+
+func (b *redisSessionBackend) del(ctx context.Context, id string) error {
+	return b.client.Del(ctx, b.prefix+id).Err()
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/keyring"
+)
+
+// runSession 模拟一次经过 Sessions 中间件的请求，返回响应设置的 cookie
+func runSession(t *testing.T, store SessionStore, cookies []*http.Cookie, handler func(*Session)) []*http.Cookie {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := serve(req, Sessions("sid", store), func(c *gyarn.Context) {
+		handler(GetSession(c))
+		c.String(http.StatusOK, "ok")
+	})
+	return w.Result().Cookies()
+}
+
+func TestSessionStores(t *testing.T) {
+	ring, _ := keyring.New([]byte("secret"))
+	stores := map[string]SessionStore{
+		"cookie": NewCookieSessionStore(ring, nil),
+		"memory": NewMemorySessionStore(nil),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			cookies := runSession(t, store, nil, func(s *Session) {
+				if !s.IsNew {
+					t.Error("第一次请求应为新会话")
+				}
+				s.Set("user_id", int64(7))
+				s.AddFlash("保存成功")
+			})
+			if len(cookies) != 1 || cookies[0].Name != "sid" || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+				t.Fatalf("会话 cookie 不正确: %v", cookies)
+			}
+
+			cookies = runSession(t, store, cookies, func(s *Session) {
+				if s.IsNew {
+					t.Error("第二次请求应读取到已有会话")
+				}
+				if v, _ := s.Get("user_id").(int64); v != 7 {
+					t.Errorf("user_id = %v，期望 7", s.Get("user_id"))
+				}
+				if flashes := s.Flashes(); len(flashes) != 1 || flashes[0] != "保存成功" {
+					t.Errorf("Flashes() = %v", flashes)
+				}
+			})
+
+			runSession(t, store, cookies, func(s *Session) {
+				if flashes := s.Flashes(); len(flashes) != 0 {
+					t.Errorf("闪存消息读取后应被删除，实际 %v", flashes)
+				}
+				s.Destroy()
+			})
+		})
+	}
+}
+
+func TestSessionTampered(t *testing.T) {
+	ring, _ := keyring.New([]byte("secret"))
+	store := NewCookieSessionStore(ring, nil)
+	cookies := runSession(t, store, nil, func(s *Session) { s.Set("role", "user") })
+
+	other, _ := keyring.New([]byte("other"))
+	runSession(t, NewCookieSessionStore(other, nil), cookies, func(s *Session) {
+		if !s.IsNew || s.Get("role") != nil {
+			t.Error("其他密钥签发的会话应被视为无效")
+		}
+	})
+}
+
+func TestSessionRegenerate(t *testing.T) {
+	store := NewMemorySessionStore(nil)
+	cookies := runSession(t, store, nil, func(s *Session) { s.Set("a", 1) })
+	oldID := cookies[0].Value
+
+	cookies = runSession(t, store, cookies, func(s *Session) { s.Regenerate() })
+	if cookies[0].Value == oldID {
+		t.Fatal("Regenerate 后会话 ID 应变化")
+	}
+	if store.Len() != 1 {
+		t.Errorf("旧会话应被删除，当前会话数 %d", store.Len())
+	}
+}
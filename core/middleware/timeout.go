@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+// TimeoutConfig 超时中间件配置
+type TimeoutConfig struct {
+	// Timeout 处理时限，从进入最外层超时中间件开始计算，默认 30 秒
+	Timeout time.Duration
+	// StatusCode 超时响应的状态码，默认 503，也可以使用 504
+	StatusCode int
+	// Handler 输出超时响应，默认通过 c.AbortWithError 返回 StatusCode 和 "请求处理超时"，由错误渲染器统一输出
+	Handler func(c *gyarn.Context)
+	// Skip 返回 true 时不限制时间，如文件下载、SSE、WebSocket 等长连接接口
+	Skip func(*gyarn.Context) bool
+}
+
+// DefaultTimeoutConfig 返回默认配置：30 秒超时，返回 503，跳过 WebSocket 和 SSE 请求
+func DefaultTimeoutConfig() *TimeoutConfig {
+	return &TimeoutConfig{
+		Timeout:    30 * time.Second,
+		StatusCode: http.StatusServiceUnavailable,
+		Skip:       IsStreamingRequest,
+	}
+}
+
+// IsStreamingRequest 判断请求是否为 WebSocket 等协议升级或 SSE 订阅（Accept 包含 text/event-stream）
+// 这类长连接不能缓冲输出，DefaultTimeoutConfig 默认跳过；自定义 Skip 时可以组合使用
+func IsStreamingRequest(c *gyarn.Context) bool {
+	return c.GetHeader("Upgrade") != "" || strings.Contains(c.GetHeader("Accept"), gyarn.MIMEEventStream)
+}
+
+// Timeout 超时中间件，处理时间超过 d 时返回 503，WebSocket 和 SSE 请求不受限制
+func Timeout(d time.Duration) HandlerFunc {
+	config := DefaultTimeoutConfig()
+	config.Timeout = d
+	return TimeoutWithConfig(config)
+}
+
+// TimeoutWithConfig 超时中间件，为 Request.Context() 设置截止时间，并缓冲处理链的输出
+// 超过时限后丢弃已缓冲的输出，改为返回超时响应
+//
+// 上下文在请求结束后会被回收复用，不能在处理函数仍在运行时提前返回，因此超时中间件不会强行中断处理函数，
+// 而是依靠 context 取消：处理函数应把 c 传给数据库、HTTP 客户端等调用（如 db.WithContext(c)），
+// 使它们在超时后立即返回
+//
+// 单个路由可以再注册一个超时中间件来覆盖全局设置（延长或缩短），新的时限同样从请求开始计算：
+//
+//	r.Use(middleware.Timeout(5 * time.Second))
+//	r.GET("/export", middleware.Timeout(2*time.Minute), exportHandler)
+//
+// 输出被完整缓冲，不适用于流式响应和 WebSocket，应通过 Skip 跳过（默认配置已跳过 IsStreamingRequest）
+func TimeoutWithConfig(config *TimeoutConfig) HandlerFunc {
+	if config == nil {
+		config = DefaultTimeoutConfig()
+	}
+	cfg := *config
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.StatusCode == 0 {
+		cfg.StatusCode = http.StatusServiceUnavailable
+	}
+	if cfg.Handler == nil {
+		cfg.Handler = func(c *gyarn.Context) {
+			c.AbortWithError(gyarn.NewHTTPError(cfg.StatusCode, "请求处理超时").WithError(context.DeadlineExceeded))
+		}
+	}
+
+	return func(c *gyarn.Context) {
+		if cfg.Skip != nil && cfg.Skip(c) {
+			c.Next()
+			return
+		}
+
+		parent := c.Request.Context()
+		if scope, ok := parent.Value(timeoutScopeKey{}).(*timeoutScope); ok {
+			// 外层已有超时中间件：只替换截止时间，由外层负责缓冲和输出超时响应
+			ctx, cancel := scope.withDeadline(parent, cfg.Timeout)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+			c.Next()
+			return
+		}
+
+		scope := &timeoutScope{parent: parent, start: time.Now()}
+		ctx, cancel := scope.withDeadline(context.WithValue(parent, timeoutScopeKey{}, scope), cfg.Timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		orig := c.Writer
+		tw := newTimeoutWriter(orig)
+		c.Writer = tw
+		defer func() {
+			c.Writer = orig
+			// 去掉截止时间，外层中间件（如会话保存）在请求结束前仍可使用 Request.Context()
+			c.Request = c.Request.WithContext(context.WithoutCancel(c.Request.Context()))
+		}()
+
+		c.Next()
+
+		// 内层超时中间件可能替换了上下文，以最终的上下文为准
+		if errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+			c.Writer = orig
+			c.Abort()
+			cfg.Handler(c)
+			return
+		}
+		tw.commit()
+	}
+}
+
+// timeoutScopeKey 最外层超时中间件在 Request.Context() 中的 key
+type timeoutScopeKey struct{}
+
+// timeoutScope 最外层超时中间件的状态，内层超时中间件据此重新计算截止时间
+type timeoutScope struct {
+	parent context.Context // 进入超时中间件之前的上下文，用于传递客户端断开的取消信号
+	start  time.Time
+}
+
+// withDeadline 返回截止时间为 start+d 的上下文
+// 新上下文保留 ctx 中的值，但不继承 ctx 的截止时间，取消信号来自 scope.parent
+func (s *timeoutScope) withDeadline(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithDeadline(context.WithoutCancel(ctx), s.start.Add(d))
+	stop := context.AfterFunc(s.parent, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// timeoutWriter 缓冲处理链的输出，处理正常结束后才写入底层 ResponseWriter
+type timeoutWriter struct {
+	orig    gyarn.ResponseWriter
+	header  http.Header
+	buf     bytes.Buffer
+	status  int
+	written bool
+	before  []func(gyarn.ResponseWriter)
+}
+
+var _ gyarn.ResponseWriter = (*timeoutWriter)(nil)
+
+func newTimeoutWriter(orig gyarn.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{
+		orig:   orig,
+		header: orig.Header().Clone(),
+		status: orig.Status(),
+	}
+}
+
+func (w *timeoutWriter) Header() http.Header { return w.header }
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow 执行 Before 回调并标记响应头已发送，实际发送在 commit 时进行
+func (w *timeoutWriter) WriteHeaderNow() {
+	if w.written {
+		return
+	}
+	before := w.before
+	w.before = nil
+	for i := len(before) - 1; i >= 0; i-- {
+		before[i](w)
+	}
+	w.written = true
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	return w.buf.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	return w.buf.WriteString(s)
+}
+
+func (w *timeoutWriter) Status() int { return w.status }
+
+func (w *timeoutWriter) Size() int { return w.buf.Len() }
+
+func (w *timeoutWriter) Written() bool { return w.written }
+
+func (w *timeoutWriter) Before(fn func(gyarn.ResponseWriter)) {
+	w.before = append(w.before, fn)
+}
+
+func (w *timeoutWriter) Unwrap() http.ResponseWriter { return w.orig }
+
+// Flush 输出被缓冲到处理结束，这里只标记响应头已发送
+func (w *timeoutWriter) Flush() {
+	w.WriteHeaderNow()
+}
+
+// Hijack 缓冲输出时无法接管连接
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("middleware: 超时中间件不支持接管连接，请通过 TimeoutConfig.Skip 跳过该路由")
+}
+
+func (w *timeoutWriter) Push(target string, opts *http.PushOptions) error {
+	return w.orig.Push(target, opts)
+}
+
+// commit 把缓冲的响应写入底层 ResponseWriter
+func (w *timeoutWriter) commit() {
+	w.WriteHeaderNow()
+	dst := w.orig.Header()
+	clear(dst)
+	for k, v := range w.header {
+		dst[k] = v
+	}
+	w.orig.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		w.orig.Write(w.buf.Bytes())
+	}
+}
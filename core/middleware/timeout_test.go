@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+// waitDone 模拟会响应取消信号的慢查询
+func waitDone(d time.Duration) gyarn.HandlerFunc {
+	return func(c *gyarn.Context) {
+		select {
+		case <-c.Done():
+			c.AbortWithError(c.Err())
+		case <-time.After(d):
+			c.String(http.StatusOK, "done")
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	w := serve(httptest.NewRequest("GET", "/", nil), Timeout(time.Second), func(c *gyarn.Context) {
+		c.SetHeader("X-Handler", "1")
+		c.String(http.StatusOK, "ok")
+	})
+	if w.Code != http.StatusOK || w.Body.String() != "ok" || w.Header().Get("X-Handler") != "1" {
+		t.Fatalf("未超时时应原样输出: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	start := time.Now()
+	w = serve(httptest.NewRequest("GET", "/", nil), Timeout(20*time.Millisecond), func(c *gyarn.Context) {
+		c.SetHeader("X-Handler", "1")
+		c.Writer.Write([]byte("partial"))
+		waitDone(time.Minute)(c)
+	})
+	if time.Since(start) > time.Second {
+		t.Fatal("超时后处理函数应通过 context 及时返回")
+	}
+	if w.Code != http.StatusServiceUnavailable || strings.Contains(w.Body.String(), "partial") || w.Header().Get("X-Handler") != "" {
+		t.Fatalf("超时后应丢弃已缓冲的输出并返回 503: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestTimeoutSkipsStreaming(t *testing.T) {
+	hasDeadline := func(c *gyarn.Context) {
+		if _, ok := c.Deadline(); ok {
+			c.String(http.StatusOK, "deadline")
+			return
+		}
+		c.String(http.StatusOK, "none")
+	}
+	tests := []struct {
+		header, value string
+		want          string
+	}{
+		{"Accept", "application/json", "deadline"},
+		{"Accept", "text/event-stream", "none"},
+		{"Upgrade", "websocket", "none"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(tt.header, tt.value)
+		if w := serve(req, Timeout(time.Second), hasDeadline); w.Body.String() != tt.want {
+			t.Errorf("%s: %s 截止时间 %q，期望 %q", tt.header, tt.value, w.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestTimeoutCustomResponse(t *testing.T) {
+	handler := TimeoutWithConfig(&TimeoutConfig{
+		Timeout:    10 * time.Millisecond,
+		StatusCode: http.StatusGatewayTimeout,
+		Handler: func(c *gyarn.Context) {
+			c.JSON(http.StatusGatewayTimeout, gyarn.Response{Code: 50400, Message: "稍后重试"})
+		},
+	})
+	w := serve(httptest.NewRequest("GET", "/", nil), handler, waitDone(time.Minute))
+	if w.Code != http.StatusGatewayTimeout || !strings.Contains(w.Body.String(), "50400") {
+		t.Fatalf("应使用自定义超时响应: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestTimeoutRouteOverride(t *testing.T) {
+	// 路由级超时延长全局设置
+	w := serve(httptest.NewRequest("GET", "/", nil), Timeout(10*time.Millisecond), Timeout(time.Second), waitDone(50*time.Millisecond))
+	if w.Code != http.StatusOK || w.Body.String() != "done" {
+		t.Fatalf("路由级超时应覆盖全局设置: %d %q", w.Code, w.Body.String())
+	}
+	// 路由级超时缩短全局设置
+	w = serve(httptest.NewRequest("GET", "/", nil), Timeout(time.Second), Timeout(10*time.Millisecond), waitDone(time.Minute))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("路由级超时应覆盖全局设置: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestTimeoutRequestContextAfterChain(t *testing.T) {
+	var outer *gyarn.Context
+	serve(httptest.NewRequest("GET", "/", nil), func(c *gyarn.Context) {
+		outer = c
+		c.Next()
+		if c.Request.Context().Err() != nil {
+			t.Error("处理链结束后外层中间件仍应能使用 Request.Context()")
+		}
+	}, Timeout(time.Second), RequestID(), func(c *gyarn.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	if RequestIDFromContext(outer) == "" {
+		t.Error("Request.Context() 中的值应被保留")
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/guyigood/gyweb/core/logger"
 )
 
 // AnnotationParser 注解解析器
@@ -107,7 +109,7 @@ func (p *AnnotationParser) scanFileForModels(filename string) error {
 						// 注册到OpenAPI Components
 						p.openapi.AddSchema(structName, schema)
 
-						fmt.Printf("[DEBUG] 发现并注册模型: %s\n", structName)
+						logger.Debug("[OpenAPI] 发现并注册模型", "name", structName)
 					}
 				}
 			}
@@ -3,9 +3,12 @@ package openapi
 import (
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/guyigood/gyweb/core/engine"
 	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/logger"
 	"github.com/guyigood/gyweb/core/middleware"
 )
 
@@ -63,7 +66,7 @@ func NewEngineExtension(e *engine.Engine, config ...OpenAPIConfig) *EngineExtens
 		// 这里简化逻辑：只要用户设置了基本信息，就认为想要启用OpenAPI
 	}
 
-	fmt.Printf("[DEBUG OpenAPI] 配置: Enabled=%v, DocsPath=%s\n", cfg.Enabled, cfg.DocsPath)
+	logger.Debug("[OpenAPI] 配置", "enabled", cfg.Enabled, "docsPath", cfg.DocsPath)
 
 	openapi := New().
 		SetInfo(Info{
@@ -85,7 +88,7 @@ func NewEngineExtension(e *engine.Engine, config ...OpenAPIConfig) *EngineExtens
 	if cfg.Enabled {
 		ext.registerRoutes()
 	} else {
-		fmt.Printf("[DEBUG OpenAPI] OpenAPI已禁用，跳过路由注册\n")
+		logger.Debug("[OpenAPI] OpenAPI已禁用，跳过路由注册")
 	}
 
 	return ext
@@ -93,9 +96,7 @@ func NewEngineExtension(e *engine.Engine, config ...OpenAPIConfig) *EngineExtens
 
 // registerRoutes 注册文档路由
 func (ext *EngineExtension) registerRoutes() {
-	fmt.Printf("[DEBUG OpenAPI] 开始注册文档路由\n")
-	fmt.Printf("[DEBUG OpenAPI] DocsPath: %s\n", ext.config.DocsPath)
-	fmt.Printf("[DEBUG OpenAPI] JSONPath: %s\n", ext.config.JSONPath)
+	logger.Debug("[OpenAPI] 开始注册文档路由", "docsPath", ext.config.DocsPath, "jsonPath", ext.config.JSONPath)
 
 	// 注册OpenAPI JSON端点
 	ext.engine.GET(ext.config.JSONPath, func(c *gyarn.Context) {
@@ -116,7 +117,7 @@ func (ext *EngineExtension) registerRoutes() {
 		c.HTML(http.StatusOK, html)
 	})
 
-	fmt.Printf("[DEBUG OpenAPI] 文档路由注册完成\n")
+	logger.Debug("[OpenAPI] 文档路由注册完成")
 }
 
 // GetOpenAPI 获取OpenAPI实例
@@ -156,7 +157,7 @@ func (ext *EngineExtension) AddSchema(name string, schema *Schema) *EngineExtens
 
 // GenerateFromAnnotations 从注解生成文档
 func (ext *EngineExtension) GenerateFromAnnotations(sourceDir string) error {
-	fmt.Printf("[DEBUG OpenAPI] 开始解析注解，源目录: %s\n", sourceDir)
+	logger.Debug("[OpenAPI] 开始解析注解", "dir", sourceDir)
 
 	// 使用增强的注解解析器
 	parser := NewAnnotationParser(ext.openapi)
@@ -167,7 +168,7 @@ func (ext *EngineExtension) GenerateFromAnnotations(sourceDir string) error {
 		return fmt.Errorf("解析注解失败: %v", err)
 	}
 
-	fmt.Printf("[DEBUG OpenAPI] 注解解析完成\n")
+	logger.Debug("[OpenAPI] 注解解析完成")
 	return nil
 }
 
@@ -175,7 +176,7 @@ func (ext *EngineExtension) GenerateFromAnnotations(sourceDir string) error {
 func (ext *EngineExtension) RegisterModel(name string, model interface{}) *EngineExtension {
 	schema := ext.openapi.GenerateFromStruct(model)
 	ext.openapi.AddSchema(name, schema)
-	fmt.Printf("[DEBUG OpenAPI] 手动注册模型: %s\n", name)
+	logger.Debug("[OpenAPI] 手动注册模型", "name", name)
 	return ext
 }
 
@@ -192,12 +193,63 @@ func (ext *EngineExtension) AutoDiscoverModels(packagePaths ...string) *EngineEx
 	for _, packagePath := range packagePaths {
 		err := ext.GenerateFromAnnotations(packagePath)
 		if err != nil {
-			fmt.Printf("[WARNING OpenAPI] 自动发现模型失败 %s: %v\n", packagePath, err)
+			logger.Warn("[OpenAPI] 自动发现模型失败", "package", packagePath, "error", err)
 		}
 	}
 	return ext
 }
 
+// RouteDiff 文档与引擎实际注册路由的差异
+// 路由格式为 "GET /api/db/page/:table"，路径参数 {table} 与 :table 视为相同
+type RouteDiff struct {
+	Undocumented []string // 已注册但没有文档的路由
+	Unregistered []string // 有文档但引擎中未注册的路由
+}
+
+// CheckRoutes 对比文档中的路由与引擎中实际注册的路由
+// 文档自身的路由（DocsPath、JSONPath）不参与对比，HEAD/OPTIONS 路由不要求有文档
+func (ext *EngineExtension) CheckRoutes() RouteDiff {
+	registered := make(map[string]string)
+	for _, rt := range ext.engine.Routes() {
+		if rt.Path == ext.config.DocsPath || rt.Path == ext.config.JSONPath {
+			continue
+		}
+		registered[routeKey(rt.Method, rt.Path)] = rt.Method + " " + rt.Path
+	}
+
+	documented := make(map[string]string)
+	for _, route := range ext.openapi.routes {
+		documented[routeKey(route.Method, route.Path)] = route.Method + " " + route.Path
+	}
+
+	var diff RouteDiff
+	for key, route := range registered {
+		if _, ok := documented[key]; !ok && !strings.HasPrefix(key, "HEAD ") && !strings.HasPrefix(key, "OPTIONS ") {
+			diff.Undocumented = append(diff.Undocumented, route)
+		}
+	}
+	for key, route := range documented {
+		if _, ok := registered[key]; !ok {
+			diff.Unregistered = append(diff.Unregistered, route)
+		}
+	}
+	sort.Strings(diff.Undocumented)
+	sort.Strings(diff.Unregistered)
+	return diff
+}
+
+// routeKey 生成用于对比的路由键，统一路径参数写法
+func routeKey(method, path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		switch {
+		case strings.HasPrefix(part, ":"), strings.HasPrefix(part, "*"):
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.ToUpper(method) + " " + strings.Join(parts, "/")
+}
+
 // 为引擎添加OpenAPI支持的便捷方法
 
 // EnableOpenAPI 为引擎启用OpenAPI支持
@@ -0,0 +1,48 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guyigood/gyweb/core/engine"
+	"github.com/guyigood/gyweb/core/gyarn"
+)
+
+func TestCheckRoutes(t *testing.T) {
+	r := engine.New()
+	handler := func(c *gyarn.Context) {}
+	r.GET("/api/db/page/:table", handler)
+	r.GET("/files/*path", handler)
+	r.POST("/api/users", handler)
+	r.HEAD("/api/users", handler)
+	r.OPTIONS("/api/users", handler)
+
+	ext := NewEngineExtension(r)
+	ext.AddRoute("GET", "/api/db/page/{table}", APIDoc{Summary: "分页查询"})
+	ext.AddRoute("get", "/files/{path}", APIDoc{Summary: "下载文件"})
+	ext.AddRoute("DELETE", "/api/users/{id}", APIDoc{Summary: "删除用户"})
+
+	diff := ext.CheckRoutes()
+	// :table、*path 与 {table}、{path} 视为相同；HEAD、OPTIONS 和文档自身的路由不要求有文档
+	if want := []string{"POST /api/users"}; !reflect.DeepEqual(diff.Undocumented, want) {
+		t.Errorf("Undocumented = %v，期望 %v", diff.Undocumented, want)
+	}
+	if want := []string{"DELETE /api/users/{id}"}; !reflect.DeepEqual(diff.Unregistered, want) {
+		t.Errorf("Unregistered = %v，期望 %v", diff.Unregistered, want)
+	}
+}
+
+func TestRouteKey(t *testing.T) {
+	tests := []struct{ method, path, want string }{
+		{"get", "/api/db/page/:table", "GET /api/db/page/{table}"},
+		{"GET", "/api/db/page/{table}", "GET /api/db/page/{table}"},
+		{"GET", "/static/*filepath", "GET /static/{filepath}"},
+		{"POST", "/users/:id/roles/:role", "POST /users/{id}/roles/{role}"},
+		{"GET", "/", "GET /"},
+	}
+	for _, tt := range tests {
+		if got := routeKey(tt.method, tt.path); got != tt.want {
+			t.Errorf("routeKey(%q, %q) = %q，期望 %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
@@ -1,15 +1,16 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
 	"strings"
 	"time"
 
-	"github.com/bwmarrin/snowflake"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/guyigood/gyweb/core/middleware"
+	"github.com/guyigood/gyweb/core/utils/common"
 )
 
 // 修改说明：
@@ -19,7 +20,8 @@ import (
 
 // DB 数据库连接结构
 type DB struct {
-	db *sql.DB
+	db  *sql.DB
+	ctx context.Context // 为 nil 时使用 context.Background()
 	// 查询构建器字段
 	table           string
 	fields          []string
@@ -68,6 +70,22 @@ func NewDB(driver, dsn string) (*DB, error) {
 	return &DB{db: db}, nil
 }
 
+// WithContext 返回使用 ctx 执行 SQL 的副本，ctx 取消或超时后正在执行的语句会被中断
+// *gyarn.Context 实现了 context.Context，可直接传入：db.WithContext(c).Table("user").All()
+func (db *DB) WithContext(ctx context.Context) *DB {
+	cp := *db
+	cp.ctx = ctx
+	return &cp
+}
+
+// context 返回执行 SQL 使用的上下文
+func (db *DB) context() context.Context {
+	if db.ctx == nil {
+		return context.Background()
+	}
+	return db.ctx
+}
+
 // Table 指定表名
 func (db *DB) Table(name string) *DB {
 	db.resetQuery()
@@ -117,7 +135,7 @@ func (db *DB) Inc(field string, val float64) (sql.Result, error) {
 	}
 
 	// 调试输出
-	middleware.DebugSQL(sql, args...)
+	middleware.DebugSQLContext(db.ctx, sql, args...)
 	
 	// 统一记录执行的 SQL 语句，便于调试和日志追踪
 	if len(args) > 0 {
@@ -127,7 +145,7 @@ func (db *DB) Inc(field string, val float64) (sql.Result, error) {
 	}
 
 	// 执行 SQL 语句
-	result, err := db.db.Exec(sql, args...)
+	result, err := db.db.ExecContext(db.context(), sql, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to increment field %s: %w", field, err)
 	}
@@ -160,7 +178,7 @@ func (db *DB) Sum(field string) (float64, error) {
 	}
 
 	// 调试输出
-	middleware.DebugSQL(sql, args...)
+	middleware.DebugSQLContext(db.ctx, sql, args...)
 	
 	// 统一记录执行的 SQL 语句，便于调试和日志追踪
 	if len(args) > 0 {
@@ -171,7 +189,7 @@ func (db *DB) Sum(field string) (float64, error) {
 
 	// 执行查询
 	var sum float64
-	err := db.db.QueryRow(sql, args...).Scan(&sum)
+	err := db.db.QueryRowContext(db.context(), sql, args...).Scan(&sum)
 	if err != nil {
 		return 0.0, fmt.Errorf("failed to calculate sum for field %s: %w", field, err)
 	}
@@ -325,8 +343,8 @@ func (db *DB) buildQuery() (string, []interface{}) {
 func (db *DB) Get() (MapModel, error) {
 	db.limit = 1
 	sql, args := db.buildQuery()
-	middleware.DebugSQL(sql, args...)
-	rows, err := db.db.Query(sql, args...)
+	middleware.DebugSQLContext(db.ctx, sql, args...)
+	rows, err := db.db.QueryContext(db.context(), sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -367,8 +385,8 @@ func (db *DB) Get() (MapModel, error) {
 // All 获取多条记录到 map 切片
 func (db *DB) All() ([]MapModel, error) {
 	sql, args := db.buildQuery()
-	middleware.DebugSQL(sql, args...)
-	rows, err := db.db.Query(sql, args...)
+	middleware.DebugSQLContext(db.ctx, sql, args...)
+	rows, err := db.db.QueryContext(db.context(), sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -410,8 +428,8 @@ func (db *DB) All() ([]MapModel, error) {
 func (db *DB) Count() (int64, error) {
 	db.fields = []string{"COUNT(*)"}
 	sql, args := db.buildQuery()
-	middleware.DebugSQL(sql, args...)
-	rows, err := db.db.Query(sql, args...)
+	middleware.DebugSQLContext(db.ctx, sql, args...)
+	rows, err := db.db.QueryContext(db.context(), sql, args...)
 	if err != nil {
 		return 0, err
 	}
@@ -469,14 +487,14 @@ func (db *DB) Insert(data interface{}) (sql.Result, error) {
 		strings.Join(fields, ", "),
 		strings.Join(placeholders, ", "))
 
-	middleware.DebugSQL(sql, args...)
+	middleware.DebugSQLContext(db.ctx, sql, args...)
 	// 统一记录执行的 SQL 语句，便于调试和日志追踪
 	if len(args) > 0 {
 		db.LastSql = fmt.Sprintf(sql, args...)
 	} else {
 		db.LastSql = sql
 	}
-	result, err := db.db.Exec(sql, args...)
+	result, err := db.db.ExecContext(db.context(), sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -523,14 +541,14 @@ func (db *DB) Update(data interface{}) (sql.Result, error) {
 		args = append(args, db.whereArgs...)
 	}
 
-	middleware.DebugSQL(sql, args...)
+	middleware.DebugSQLContext(db.ctx, sql, args...)
 	// 统一记录执行的 SQL 语句，便于调试和日志追踪
 	if len(args) > 0 {
 		db.LastSql = fmt.Sprintf(sql, args...)
 	} else {
 		db.LastSql = sql
 	}
-	result, err := db.db.Exec(sql, args...)
+	result, err := db.db.ExecContext(db.context(), sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -546,14 +564,14 @@ func (db *DB) Delete() (sql.Result, error) {
 		sql += " WHERE " + strings.Join(db.where, " AND ")
 	}
  
-	middleware.DebugSQL(sql, db.whereArgs...)
+	middleware.DebugSQLContext(db.ctx, sql, db.whereArgs...)
 	// 统一记录执行的 SQL 语句，便于调试和日志追踪
 	if len(db.whereArgs) > 0 {
 		db.LastSql = fmt.Sprintf(sql, db.whereArgs...)
 	} else {
 		db.LastSql = sql
 	}
-	result, err := db.db.Exec(sql, db.whereArgs...)
+	result, err := db.db.ExecContext(db.context(), sql, db.whereArgs...)
 	if err != nil {
 		return nil, err
 	}
@@ -563,13 +581,13 @@ func (db *DB) Delete() (sql.Result, error) {
 
 // Transaction 执行事务函数
 func (db *DB) Transaction(fn func(*DB) error) error {
-	tx, err := db.db.Begin()
+	tx, err := db.db.BeginTx(db.context(), nil)
 	if err != nil {
 		return err
 	}
 
 	// 创建事务DB对象
-	txDB := &DB{db: db.db}
+	txDB := &DB{db: db.db, ctx: db.ctx}
 	txDB.db = db.db
 
 	defer func() {
@@ -594,14 +612,14 @@ func (db *DB) Close() error {
 
 // query 查询sql语句
 func (db *DB) Query(sql string, args ...any) ([]MapModel, error) {
-	middleware.DebugSQL(sql, args...)
+	middleware.DebugSQLContext(db.ctx, sql, args...)
 	// 统一记录执行的 SQL 语句，便于调试和日志追踪
 	if len(args) > 0 {
 		db.LastSql = fmt.Sprintf(sql, args...)
 	} else {
 		db.LastSql = sql
 	}
-	rows, err := db.db.Query(sql, args...)
+	rows, err := db.db.QueryContext(db.context(), sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -641,34 +659,30 @@ func (db *DB) Query(sql string, args ...any) ([]MapModel, error) {
 
 // exec 执行sql语句
 func (db *DB) Exec(sql string, args ...any) (sql.Result, error) {
-	middleware.DebugSQL(sql, args...)
+	middleware.DebugSQLContext(db.ctx, sql, args...)
 	// 统一记录执行的 SQL 语句，便于调试和日志追踪
 	if len(args) > 0 {
 		db.LastSql = fmt.Sprintf(sql, args...)
 	} else {
 		db.LastSql = sql
 	}
-	return db.db.Exec(sql, args...)
+	return db.db.ExecContext(db.context(), sql, args...)
 }
 
-// 雪花算法计算id
+// 雪花算法计算id，与 common.SnowflakeID 共享同一个节点，节点号通过 common.SetSnowflakeNode 设置
 func (db *DB) SnowflakeID() (int64, error) {
-	node, err := snowflake.NewNode(1)
-	if err != nil {
-		return 0, err
-	}
-	return node.Generate().Int64(), nil
+	return common.SnowflakeID(), nil
 }
 
 func (db *DB) QueryRow(sql string, args ...any) (MapModel, error) {
-	middleware.DebugSQL(sql, args...)
+	middleware.DebugSQLContext(db.ctx, sql, args...)
 	// 统一记录执行的 SQL 语句，便于调试和日志追踪
 	if len(args) > 0 {
 		db.LastSql = fmt.Sprintf(sql, args...)
 	} else {
 		db.LastSql = sql
 	}
-	rows, err := db.db.Query(sql, args...)
+	rows, err := db.db.QueryContext(db.context(), sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -708,7 +722,7 @@ func (db *DB) QueryRow(sql string, args ...any) (MapModel, error) {
 }
 
 func (db *DB) Begin() (*sql.Tx, error) {
-	tx, err := db.db.Begin()
+	tx, err := db.db.BeginTx(db.context(), nil)
 	return tx, err
 }
 
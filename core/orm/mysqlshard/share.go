@@ -8,9 +8,10 @@ import (
 	"sync"
 	"time"
 
-	"github.com/bwmarrin/snowflake"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/guyigood/gyweb/core/logger"
 	"github.com/guyigood/gyweb/core/middleware"
+	"github.com/guyigood/gyweb/core/utils/common"
 )
 
 // ShardConfig 分表配置
@@ -224,7 +225,7 @@ func (db *DB) createNewShardTable(baseTable string, shardNum int) error {
 	db.shardConfig.ShardTables[baseTable] = shardNum
 	db.shardConfig.mutex.Unlock()
 
-	middleware.DebugSQL("Created new shard table: %s", newTableName)
+	logger.Debug("Created new shard table", "table", newTableName)
 	return nil
 }
 
@@ -831,11 +832,7 @@ func (db *DB) GetShardInfo(baseTable string) (map[string]interface{}, error) {
 	return info, nil
 }
 
-// 雪花算法计算id
+// 雪花算法计算id，与 common.SnowflakeID 共享同一个节点，节点号通过 common.SetSnowflakeNode 设置
 func (db *DB) SnowflakeID() (int64, error) {
-	node, err := snowflake.NewNode(1)
-	if err != nil {
-		return 0, err
-	}
-	return node.Generate().Int64(), nil
+	return common.SnowflakeID(), nil
 }
@@ -1,6 +1,8 @@
 package router
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/guyigood/gyweb/core/gyarn"
@@ -14,21 +16,26 @@ type node struct {
 	Pattern  string        // 导出字段
 	part     string        // 路由中的一部分
 	children []*node       // 子节点
-	Handlers []HandlerFunc // 导出字段
+	Handlers []HandlerFunc // 导出字段，完整路由节点上的处理链
 	isWild   bool          // 是否模糊匹配（包含:或*）
+	nType    int           // 节点类型：静态、参数或通配符
 }
 
 // Router 路由接口
 type Router interface {
 	AddRoute(method string, pattern string, handlers ...HandlerFunc)
+	SetHandlers(method string, pattern string, handlers ...HandlerFunc)
 	GetRoute(method string, path string) (*node, map[string]string)
+	Lookup(method string, path string, params map[string]string) *node
 	GetHandlers(key string) []HandlerFunc
+	AllowedMethods(path string) []string
 }
 
 // router 路由实现
 type router struct {
 	roots    map[string]*node         // 每种请求方法的根节点
 	handlers map[string][]HandlerFunc // 路由处理函数
+	nodes    map[string]*node         // 完整路由对应的节点，键与 handlers 相同
 }
 
 // New 创建路由实例
@@ -36,10 +43,12 @@ func New() Router {
 	return &router{
 		roots:    make(map[string]*node),
 		handlers: make(map[string][]HandlerFunc),
+		nodes:    make(map[string]*node),
 	}
 }
 
 // parsePattern 解析路由模式
+// 通配符 * 之后的部分会被忽略，注册时由 validatePattern 保证 * 位于末尾
 func parsePattern(pattern string) []string {
 	parts := strings.Split(pattern, "/")
 	result := make([]string, 0)
@@ -54,8 +63,43 @@ func parsePattern(pattern string) []string {
 	return result
 }
 
+// splitPath 拆分请求路径，与 parsePattern 不同，请求路径中的 * 没有特殊含义
+func splitPath(path string) []string {
+	parts := strings.Split(path, "/")
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// validatePattern 校验路由模式，不合法时 panic
+func validatePattern(pattern string) {
+	if pattern == "" || pattern[0] != '/' {
+		panic(fmt.Sprintf("gyweb: 路由 %q 必须以 / 开头", pattern))
+	}
+	parts := splitPath(pattern)
+	for i, part := range parts {
+		switch part[0] {
+		case ':':
+			if len(part) == 1 {
+				panic(fmt.Sprintf("gyweb: 路由 %q 中的参数缺少名称", pattern))
+			}
+		case '*':
+			if i != len(parts)-1 {
+				panic(fmt.Sprintf("gyweb: 路由 %q 中的通配符 %s 必须位于末尾", pattern, part))
+			}
+		}
+	}
+}
+
 // AddRoute 添加路由
+// 路由模式不合法或与已注册路由冲突时 panic，冲突包括：
+// 同一方法重复注册相同路由、同一位置使用不同名称的参数或通配符
 func (r *router) AddRoute(method string, pattern string, handlers ...HandlerFunc) {
+	validatePattern(pattern)
 	parts := parsePattern(pattern)
 	key := method + "-" + pattern
 
@@ -67,43 +111,83 @@ func (r *router) AddRoute(method string, pattern string, handlers ...HandlerFunc
 	}
 
 	// 插入节点
-	root.insert(pattern, parts, 0)
+	n := root.insert(method, pattern, parts, 0)
+	n.Handlers = handlers
 	r.handlers[key] = handlers
+	r.nodes[key] = n
+}
+
+// SetHandlers 替换已注册路由的处理链（如路由组在注册路由后又添加了中间件）
+func (r *router) SetHandlers(method string, pattern string, handlers ...HandlerFunc) {
+	key := method + "-" + pattern
+	r.handlers[key] = handlers
+	if n, ok := r.nodes[key]; ok {
+		n.Handlers = handlers
+	}
 }
 
 // GetRoute 获取路由
+// 匹配优先级：静态路径 > 参数(:name) > 通配符(*name)，高优先级分支匹配失败时回退到低优先级分支
 func (r *router) GetRoute(method string, path string) (*node, map[string]string) {
-	searchParts := parsePattern(path)
 	params := make(map[string]string)
-	root, ok := r.roots[method]
+	n := r.Lookup(method, path, params)
+	if n == nil {
+		return nil, nil
+	}
+	return n, params
+}
 
+// Lookup 查找路由，并把路由参数写入 params（可以为 nil）
+// 匹配过程直接在 path 上按段扫描，不拆分字符串，也不重新解析路由模式，
+// 调用方复用 params 时整个查找过程没有内存分配
+func (r *router) Lookup(method string, path string, params map[string]string) *node {
+	root, ok := r.roots[method]
 	if !ok {
-		return nil, nil
+		return nil
 	}
+	return root.search(path, params)
+}
 
-	n := root.search(searchParts, 0)
-	if n != nil {
-		parts := parsePattern(n.Pattern)
-		for index, part := range parts {
-			if part[0] == ':' {
-				params[part[1:]] = searchParts[index]
-			}
-			if part[0] == '*' && len(part) > 1 {
-				params[part[1:]] = strings.Join(searchParts[index:], "/")
-				break
-			}
+// AllowedMethods 返回能够匹配该路径的所有请求方法（已排序）
+// 用于生成 405 和 OPTIONS 响应的 Allow 头
+func (r *router) AllowedMethods(path string) []string {
+	methods := make([]string, 0, len(r.roots))
+	for method, root := range r.roots {
+		if root.search(path, nil) != nil {
+			methods = append(methods, method)
 		}
-		return n, params
 	}
+	sort.Strings(methods)
+	return methods
+}
 
-	return nil, nil
+// 节点类型，数值越小匹配优先级越高
+const (
+	kindStatic = iota
+	kindParam
+	kindCatchAll
+)
+
+// partKind 返回路由片段对应的节点类型
+func partKind(part string) int {
+	switch part[0] {
+	case ':':
+		return kindParam
+	case '*':
+		return kindCatchAll
+	default:
+		return kindStatic
+	}
 }
 
-// insert 插入节点
-func (n *node) insert(pattern string, parts []string, height int) {
+// insert 插入节点，返回完整路由对应的节点
+func (n *node) insert(method string, pattern string, parts []string, height int) *node {
 	if len(parts) == height {
+		if n.Pattern != "" {
+			panic(fmt.Sprintf("gyweb: 路由冲突: %s %s 与已注册的 %s %s 重复", method, pattern, method, n.Pattern))
+		}
 		n.Pattern = pattern
-		return
+		return n
 	}
 
 	part := parts[height]
@@ -112,16 +196,22 @@ func (n *node) insert(pattern string, parts []string, height int) {
 		child = &node{
 			part:   part,
 			isWild: part[0] == ':' || part[0] == '*',
+			nType:  partKind(part),
+		}
+		if conflict := n.conflictChild(child); conflict != nil {
+			panic(fmt.Sprintf("gyweb: 路由冲突: %s %s 中的 %s 与已注册路由中的 %s 位于同一位置",
+				method, pattern, part, conflict.part))
 		}
-		n.children = append(n.children, child)
+		n.addChild(child)
 	}
-	child.insert(pattern, parts, height+1)
+	return child.insert(method, pattern, parts, height+1)
 }
 
-// search 搜索节点
-func (n *node) search(parts []string, height int) *node {
-	// 如果已经到达路径末尾或遇到通配符
-	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+// search 在当前节点下匹配剩余路径 rest，成功时把参数写入 params
+// 子节点已按优先级排序，参数只在匹配成功后回填，回退时不会留下脏数据
+func (n *node) search(rest string, params map[string]string) *node {
+	rest = strings.TrimLeft(rest, "/")
+	if rest == "" {
 		// 检查当前节点是否有Pattern（即是否是一个完整的路由）
 		if n.Pattern == "" {
 			return nil
@@ -129,38 +219,85 @@ func (n *node) search(parts []string, height int) *node {
 		return n
 	}
 
-	part := parts[height]
-	children := n.matchChildren(part)
+	segment, tail := rest, ""
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		segment, tail = rest[:i], rest[i:]
+	}
 
-	for _, child := range children {
-		result := child.search(parts, height+1)
-		if result != nil {
-			return result
+	for _, child := range n.children {
+		switch child.nType {
+		case kindStatic:
+			if child.part != segment {
+				continue
+			}
+			if result := child.search(tail, params); result != nil {
+				return result
+			}
+		case kindParam:
+			if result := child.search(tail, params); result != nil {
+				if params != nil {
+					params[child.part[1:]] = segment
+				}
+				return result
+			}
+		case kindCatchAll:
+			if child.Pattern == "" {
+				continue
+			}
+			if params != nil && len(child.part) > 1 {
+				params[child.part[1:]] = catchAllValue(rest)
+			}
+			return child
 		}
 	}
 
 	return nil
 }
 
-// matchChild 匹配单个子节点
+// catchAllValue 返回通配符匹配的值，多余的斜杠会被规范化
+func catchAllValue(rest string) string {
+	if strings.Contains(rest, "//") || strings.HasSuffix(rest, "/") {
+		return strings.Join(splitPath(rest), "/")
+	}
+	return rest
+}
+
+// matchChild 查找与路由片段完全相同的子节点
 func (n *node) matchChild(part string) *node {
 	for _, child := range n.children {
-		if child.part == part || child.isWild {
+		if child.part == part {
 			return child
 		}
 	}
 	return nil
 }
 
-// matchChildren 匹配所有子节点
-func (n *node) matchChildren(part string) []*node {
-	nodes := make([]*node, 0)
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			nodes = append(nodes, child)
+// conflictChild 查找与新节点冲突的子节点
+// 同一位置只允许一个参数名和一个通配符名
+func (n *node) conflictChild(child *node) *node {
+	if child.nType == kindStatic {
+		return nil
+	}
+	for _, existing := range n.children {
+		if existing.nType == child.nType && existing.part != child.part {
+			return existing
+		}
+	}
+	return nil
+}
+
+// addChild 按优先级插入子节点：静态 > 参数 > 通配符
+func (n *node) addChild(child *node) {
+	index := len(n.children)
+	for i, existing := range n.children {
+		if existing.nType > child.nType {
+			index = i
+			break
 		}
 	}
-	return nodes
+	n.children = append(n.children, nil)
+	copy(n.children[index+1:], n.children[index:])
+	n.children[index] = child
 }
 
 // GetHandlers 获取处理函数
@@ -0,0 +1,147 @@
+package router
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestRouter 使用给定的 GET 路由创建路由器
+func newTestRouter(patterns ...string) *router {
+	r := New().(*router)
+	for _, pattern := range patterns {
+		r.AddRoute("GET", pattern)
+	}
+	return r
+}
+
+// mustPanic 断言 fn 触发 panic，且信息包含 want
+func mustPanic(t *testing.T, want string, fn func()) {
+	t.Helper()
+	defer func() {
+		err := recover()
+		if err == nil {
+			t.Fatalf("期望 panic，实际未发生")
+		}
+		if msg, _ := err.(string); !strings.Contains(msg, want) {
+			t.Fatalf("panic 信息 %q 不包含 %q", msg, want)
+		}
+	}()
+	fn()
+}
+
+func TestGetRoutePriority(t *testing.T) {
+	r := newTestRouter(
+		"/",
+		"/user/list",
+		"/user/:id",
+		"/user/:id/profile",
+		"/files/list",
+		"/files/:name/info",
+		"/files/*path",
+		"/static/*filepath",
+	)
+
+	tests := []struct {
+		name    string
+		path    string
+		pattern string
+		params  map[string]string
+	}{
+		{"根路径", "/", "/", map[string]string{}},
+		{"静态优先于参数", "/user/list", "/user/list", map[string]string{}},
+		{"参数匹配", "/user/42", "/user/:id", map[string]string{"id": "42"}},
+		{"参数后的静态片段", "/user/42/profile", "/user/:id/profile", map[string]string{"id": "42"}},
+		{"静态优先于通配符", "/files/list", "/files/list", map[string]string{}},
+		{"参数优先于通配符", "/files/a.txt/info", "/files/:name/info", map[string]string{"name": "a.txt"}},
+		{"参数分支失败回退到通配符", "/files/a.txt/raw", "/files/*path", map[string]string{"path": "a.txt/raw"}},
+		{"静态分支失败回退到通配符", "/files/list/more", "/files/*path", map[string]string{"path": "list/more"}},
+		{"通配符匹配多级路径", "/static/css/app.css", "/static/*filepath", map[string]string{"filepath": "css/app.css"}},
+		{"请求路径中的星号没有特殊含义", "/static/*/a", "/static/*filepath", map[string]string{"filepath": "*/a"}},
+		{"多余的斜杠被忽略", "//user//42/", "/user/:id", map[string]string{"id": "42"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, params := r.GetRoute("GET", tt.path)
+			if n == nil {
+				t.Fatalf("%s 未匹配到路由", tt.path)
+			}
+			if n.Pattern != tt.pattern {
+				t.Fatalf("%s 匹配到 %s，期望 %s", tt.path, n.Pattern, tt.pattern)
+			}
+			if !reflect.DeepEqual(params, tt.params) {
+				t.Fatalf("%s 参数为 %v，期望 %v", tt.path, params, tt.params)
+			}
+		})
+	}
+}
+
+func TestGetRouteNotFound(t *testing.T) {
+	r := newTestRouter("/user/:id", "/user/:id/profile", "/files/*path")
+
+	for _, path := range []string{"/user", "/user/42/settings", "/files", "/other"} {
+		if n, _ := r.GetRoute("GET", path); n != nil {
+			t.Errorf("%s 不应匹配，实际匹配到 %s", path, n.Pattern)
+		}
+	}
+	if n, _ := r.GetRoute("POST", "/user/42"); n != nil {
+		t.Errorf("未注册的方法不应匹配，实际匹配到 %s", n.Pattern)
+	}
+}
+
+func TestAddRouteConflict(t *testing.T) {
+	t.Run("重复注册", func(t *testing.T) {
+		r := newTestRouter("/user/:id")
+		mustPanic(t, "重复", func() { r.AddRoute("GET", "/user/:id") })
+	})
+
+	t.Run("同一位置的参数名不同", func(t *testing.T) {
+		r := newTestRouter("/user/:id")
+		mustPanic(t, ":name", func() { r.AddRoute("GET", "/user/:name/profile") })
+	})
+
+	t.Run("同一位置的通配符名不同", func(t *testing.T) {
+		r := newTestRouter("/files/*path")
+		mustPanic(t, "*file", func() { r.AddRoute("GET", "/files/*file") })
+	})
+
+	t.Run("通配符不在末尾", func(t *testing.T) {
+		r := newTestRouter()
+		mustPanic(t, "末尾", func() { r.AddRoute("GET", "/files/*path/info") })
+	})
+
+	t.Run("参数缺少名称", func(t *testing.T) {
+		r := newTestRouter()
+		mustPanic(t, "名称", func() { r.AddRoute("GET", "/user/:") })
+	})
+
+	t.Run("不以斜杠开头", func(t *testing.T) {
+		r := newTestRouter()
+		mustPanic(t, "/ 开头", func() { r.AddRoute("GET", "user") })
+	})
+
+	t.Run("不同方法可以注册相同路由", func(t *testing.T) {
+		r := newTestRouter("/user/:id")
+		r.AddRoute("POST", "/user/:id")
+		if n, _ := r.GetRoute("POST", "/user/1"); n == nil {
+			t.Fatalf("POST /user/1 未匹配")
+		}
+	})
+}
+
+func TestAllowedMethods(t *testing.T) {
+	r := newTestRouter("/user/:id")
+	r.AddRoute("PUT", "/user/:id")
+	r.AddRoute("DELETE", "/user/list")
+
+	if got, want := r.AllowedMethods("/user/1"), []string{"GET", "PUT"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AllowedMethods(/user/1) = %v，期望 %v", got, want)
+	}
+	if got, want := r.AllowedMethods("/user/list"), []string{"DELETE", "GET", "PUT"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AllowedMethods(/user/list) = %v，期望 %v", got, want)
+	}
+	if got := r.AllowedMethods("/none"); len(got) != 0 {
+		t.Errorf("AllowedMethods(/none) = %v，期望为空", got)
+	}
+}
@@ -0,0 +1,41 @@
+package dingtalk
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guyigood/gyweb/core/logger"
+	"github.com/guyigood/gyweb/core/middleware"
+)
+
+// PanicNotifier 返回把 panic 发送到钉钉群机器人的告警函数，用作 middleware.RecoveryConfig 的 Notifier
+// title 为消息标题，atMobiles 为需要 @ 的手机号；堆栈只保留前 3000 字节，避免超出消息长度限制
+func PanicNotifier(robot *DingTalk, title string, atMobiles ...string) func(*middleware.PanicInfo) {
+	if title == "" {
+		title = "服务异常"
+	}
+	return func(info *middleware.PanicInfo) {
+		stack := info.Stack
+		if len(stack) > 3000 {
+			stack = stack[:3000]
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "### %s\n\n", title)
+		fmt.Fprintf(&b, "- 时间：%s\n", info.Time.Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(&b, "- 请求：%s %s\n", info.Method, info.Path)
+		fmt.Fprintf(&b, "- 客户端：%s\n", info.IP)
+		if info.RequestID != "" {
+			fmt.Fprintf(&b, "- 请求 ID：%s\n", info.RequestID)
+		}
+		fmt.Fprintf(&b, "- 错误：%v\n", info.Err)
+		for _, mobile := range atMobiles {
+			fmt.Fprintf(&b, "\n@%s", mobile)
+		}
+		if len(stack) > 0 {
+			fmt.Fprintf(&b, "\n\n```\n%s\n```\n", stack)
+		}
+		if err := robot.SendRobotMarkdownMessage(title, b.String(), atMobiles, nil, false); err != nil {
+			logger.Warn("发送钉钉告警失败", "error", err)
+		}
+	}
+}
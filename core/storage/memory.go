@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Memory 内存存储，适用于测试
+type Memory struct {
+	mu    sync.RWMutex
+	files map[string]MemoryFile
+}
+
+// MemoryFile 内存中保存的文件
+type MemoryFile struct {
+	Data        []byte
+	ContentType string
+}
+
+// NewMemory 创建内存存储
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string]MemoryFile)}
+}
+
+// Save 保存文件
+func (m *Memory) Save(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(readerWithContext(ctx, r))
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[key] = MemoryFile{Data: data, ContentType: contentType}
+	return nil
+}
+
+// Open 打开文件
+func (m *Memory) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, ok := m.Get(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(file.Data)), nil
+}
+
+// Delete 删除文件
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, key)
+	return nil
+}
+
+// Get 返回保存的文件，便于在测试中断言
+func (m *Memory) Get(key string) (MemoryFile, bool) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return MemoryFile{}, false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	file, ok := m.files[key]
+	return file, ok
+}
+
+// Keys 返回所有文件的 key，已排序
+func (m *Memory) Keys() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]string, 0, len(m.files))
+	for key := range m.files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
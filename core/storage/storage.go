@@ -0,0 +1,43 @@
+// Package storage 文件存储后端，上传文件通过它保存到本地磁盘、内存或 S3 兼容的对象存储
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrNotFound 文件不存在
+var ErrNotFound = errors.New("storage: 文件不存在")
+
+// Storage 文件存储后端
+// key 为使用 / 分隔的相对路径，如 "avatar/2024-05-01/a.png"
+type Storage interface {
+	// Save 保存文件，size 未知时传 -1，contentType 为空时由后端自行决定
+	Save(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 打开文件，不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除文件，文件不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+// CleanKey 规范化 key：统一使用 /，去掉开头的 /，并拒绝跳出根目录的 ..
+func CleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(key, "\\", "/")
+	if key == "" {
+		return "", errors.New("storage: key 不能为空")
+	}
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" {
+		return "", errors.New("storage: key 不能为空")
+	}
+	// 拼接 / 后再 Clean，.. 无法越过根目录，但原始 key 中包含 .. 片段说明调用方有误
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." {
+			return "", errors.New("storage: key 不能包含 ..")
+		}
+	}
+	return strings.TrimPrefix(cleaned, "/"), nil
+}
@@ -0,0 +1,36 @@
+package common
+
+import (
+	"sync"
+
+	"github.com/bwmarrin/snowflake"
+)
+
+var (
+	snowflakeMu   sync.Mutex
+	snowflakeNode *snowflake.Node
+)
+
+// SetSnowflakeNode 设置雪花算法的节点号（0-1023），多实例部署时每个实例应使用不同的节点号，默认为 1
+func SetSnowflakeNode(node int64) error {
+	n, err := snowflake.NewNode(node)
+	if err != nil {
+		return err
+	}
+	snowflakeMu.Lock()
+	snowflakeNode = n
+	snowflakeMu.Unlock()
+	return nil
+}
+
+// SnowflakeID 生成雪花算法 ID，同一进程内的所有调用共享一个节点，保证不重复
+func SnowflakeID() int64 {
+	snowflakeMu.Lock()
+	if snowflakeNode == nil {
+		// 节点号 1 一定有效
+		snowflakeNode, _ = snowflake.NewNode(1)
+	}
+	n := snowflakeNode
+	snowflakeMu.Unlock()
+	return n.Generate().Int64()
+}
@@ -5,42 +5,45 @@ import (
 	"math/rand"
 	"mime/multipart"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/guyigood/gyweb/core/gyarn"
 )
 
 // UploadFile 上传文件,参数filename为上传的文件名,参数uploadPath为上传的文件路径
+// 文件大小、类型限制和存储后端由 engine.SetUploadConfig 配置，返回保存后的路径（存储后端中的 key）
 func UploadFile(c *gyarn.Context, filename string, uploadPath string) (string, error) {
 	// 获取上传的文件
 	file, err := c.FormFile(filename)
 	if err != nil {
 		return "", err
 	}
-	//默认/upload/  同时检查是否创建了目录
+	//默认/upload/
 	if uploadPath == "" {
 		uploadPath = "upload/"
 	}
-	if _, err := os.Stat(uploadPath); os.IsNotExist(err) {
-		os.MkdirAll(uploadPath, 0755)
-	}
-	// TODO: 处理文件保存逻辑，按时间生成日期目录
+	// 按时间生成日期目录，文件名使用时间戳+随机数+文件扩展名
 	date := time.Now().Format("2006-01-02")
-	uploadPath += date + "/"
-	// 创建目录
-	if _, err := os.Stat(uploadPath); os.IsNotExist(err) {
-		os.MkdirAll(uploadPath, 0755)
+	dst := path.Join(uploadPath, date, secureFileName(file.Filename))
+	if err := c.SaveUploadedFile(file, dst); err != nil {
+		return "", err
 	}
-	// 保存文件
-	return SaveFileSecure(file, uploadPath)
+	return dst, nil
+}
 
+// secureFileName 生成不含客户端路径信息的文件名
+func secureFileName(original string) string {
+	return time.Now().Format("20060102150405") + strconv.Itoa(rand.Intn(1000000)) + strings.ToLower(filepath.Ext(original))
 }
 
+// SaveFileSecure 把上传的文件以安全的文件名保存到本地目录 uploadDir，返回文件路径
 func SaveFileSecure(fh *multipart.FileHeader, uploadDir string) (string, error) {
 	// 清洗文件名和路径，文件名使用时间戳+随机数+文件扩展名
-	filename := time.Now().Format("20060102150405") + strconv.Itoa(rand.Intn(1000000)) + filepath.Ext(fh.Filename)
+	filename := secureFileName(fh.Filename)
 	dstPath := filepath.Join(uploadDir, filename)
 
 	// 验证目录
@@ -59,6 +59,8 @@ type Hub struct {
 	broadcast   chan []byte
 	register    chan *Connection
 	unregister  chan *Connection
+	quit        chan struct{}
+	closeOnce   sync.Once
 	mutex       sync.RWMutex
 }
 
@@ -121,13 +123,24 @@ func NewHub() *Hub {
 		broadcast:   make(chan []byte),
 		register:    make(chan *Connection),
 		unregister:  make(chan *Connection),
+		quit:        make(chan struct{}),
 	}
 }
 
-// Run 启动Hub
+// Run 启动Hub，调用 Close 后返回
 func (h *Hub) Run() {
 	for {
 		select {
+		case <-h.quit:
+			h.mutex.Lock()
+			for clientID, conn := range h.connections {
+				close(conn.send)
+				delete(h.connections, clientID)
+			}
+			h.mutex.Unlock()
+			log.Printf("WebSocket Hub 已关闭")
+			return
+
 		case conn := <-h.register:
 			h.mutex.Lock()
 			h.connections[conn.clientID] = conn
@@ -158,9 +171,20 @@ func (h *Hub) Run() {
 	}
 }
 
+// Close 关闭Hub
+// 向所有客户端发送关闭帧并断开连接，可与 engine.OnShutdown 配合使用
+func (h *Hub) Close() {
+	h.closeOnce.Do(func() {
+		close(h.quit)
+	})
+}
+
 // Broadcast 广播消息给所有连接
 func (h *Hub) Broadcast(message []byte) {
-	h.broadcast <- message
+	select {
+	case h.broadcast <- message:
+	case <-h.quit:
+	}
 }
 
 // SendToClient 发送消息给指定客户端
@@ -214,7 +238,12 @@ func HandleWebSocket(c *gyarn.Context, hub *Hub, config *WebSocketConfig, client
 	}
 
 	// 注册连接
-	hub.register <- connection
+	select {
+	case hub.register <- connection:
+	case <-hub.quit:
+		conn.Close()
+		return
+	}
 
 	// 调用连接建立回调
 	if config.OnConnect != nil {
@@ -229,7 +258,10 @@ func HandleWebSocket(c *gyarn.Context, hub *Hub, config *WebSocketConfig, client
 // readPump 读取消息
 func (c *Connection) readPump(config *WebSocketConfig) {
 	defer func() {
-		c.hub.unregister <- c
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.quit:
+		}
 		c.conn.Close()
 		if config.OnDisconnect != nil {
 			config.OnDisconnect(c)
@@ -10,6 +10,12 @@ import (
 	"github.com/guyigood/gyweb/core/utils/datatype"
 )
 
+// pageQuery 分页参数
+type pageQuery struct {
+	PageNo   int `form:"page_no,default=1" binding:"min=1"`
+	PageSize int `form:"page_size,default=20" binding:"min=1"`
+}
+
 // Page 分页查询数据
 // @Summary 分页查询数据
 // @Tags 数据库通用操作
@@ -21,6 +27,7 @@ import (
 // @Param sort_by query string false "排序字段"
 // @Param order query string false "排序方式"
 // @Success 200 {object} map[string]interface{} "查询结果"
+// @Failure 400 {object} map[string]interface{} "分页参数校验失败"
 // @Failure 101 {object} map[string]interface{} "参数错误"
 // @Failure 102 {object} map[string]interface{} "表名不能为空"
 // @Failure 103 {object} map[string]interface{} "获取总数失败"
@@ -34,16 +41,12 @@ func Page(c *gyarn.Context) {
 		return
 	}
 
-	pageSizeStr := c.Query("page_size")
-	pageSize := 20
-	if pageSizeStr != "" {
-		pageSize, _ = datatype.StrtoInt(pageSizeStr)
-	}
-	pageNoStr := c.Query("page_no")
-	pageNo := 1
-	if pageNoStr != "" {
-		pageNo, _ = datatype.StrtoInt(pageNoStr)
+	var pq pageQuery
+	if err := c.BindQuery(&pq); err != nil {
+		c.BindError(err)
+		return
 	}
+	pageSize, pageNo := pq.PageSize, pq.PageNo
 
 	tbinfo, err := public.GetTbInfoByTableName(tableName)
 	if err != nil {
@@ -55,8 +58,9 @@ func Page(c *gyarn.Context) {
 	defer db.Close()
 	countdb := public.GetDbConnection()
 	defer countdb.Close()
-	query := db.Table(tableName)
-	countQuery := countdb.Table(tableName)
+	// 使用请求上下文，客户端断开或超时后查询会被中断
+	query := db.GetDB().WithContext(c).Table(tableName)
+	countQuery := countdb.GetDB().WithContext(c).Table(tableName)
 	if tbinfo.JoinTable != "" {
 		query = query.Join(tbinfo.JoinTable).Select(tbinfo.JoinField + "," + tableName + ".*")
 	}
@@ -0,0 +1,15 @@
+/*
+ 已有数据库的升级脚本，新安装直接导入 db.sql 即可
+
+ 按顺序执行尚未执行过的段落
+*/
+
+SET NAMES utf8mb4;
+
+-- ----------------------------
+-- operation_log 增加 request_id，对应响应头 X-Request-ID
+-- 操作日志写入 request_id 字段，未执行本段时日志写入会失败
+-- ----------------------------
+ALTER TABLE `operation_log`
+  ADD COLUMN `request_id` varchar(128) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NULL DEFAULT NULL COMMENT '请求ID，对应响应头 X-Request-ID' AFTER `body`,
+  ADD INDEX `idx_request_id`(`request_id` ASC) USING BTREE;
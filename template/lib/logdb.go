@@ -2,15 +2,36 @@ package lib
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"sync"
 	"{project_name}/model"
 	"{project_name}/public"
 	"time"
 
 	"github.com/guyigood/gyweb/core/gyarn"
+	"github.com/guyigood/gyweb/core/logger"
 	"github.com/guyigood/gyweb/core/middleware"
 )
 
+// logWg 跟踪尚未写入完成的异步日志
+var logWg sync.WaitGroup
+
+// FlushLogDb 等待所有异步日志写入完成，供服务关闭时调用
+func FlushLogDb(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		logWg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func LogDb() middleware.HandlerFunc {
 	return func(c *gyarn.Context) {
 		// 跳过OPTIONS请求的日志记录，避免CORS预检请求时的中间件冲突
@@ -20,14 +41,6 @@ func LogDb() middleware.HandlerFunc {
 		}
 
 		t := time.Now().Format("2006-01-02 15:04:05")
-		login, ok := c.Get("login")
-		user_id := 0
-		if ok {
-			user, u_flag := login.(model.LoginUser)
-			if u_flag {
-				user_id = user.ID
-			}
-		}
 		body_str := ""
 		if c.Request.Body != nil {
 			body := c.Request.Body
@@ -38,22 +51,39 @@ func LogDb() middleware.HandlerFunc {
 			c.Request.Body = io.NopCloser(bytes.NewBuffer(body_b))
 		}
 
+		// 上下文在请求结束后会被回收复用，协程中使用副本
+		cp := c.Copy()
+
 		// 异步记录日志，使用独立的数据库连接避免冲突
+		logWg.Add(1)
 		go func() {
-			// 获取独立的数据库连接
-			dbConn := public.GetDbConnection()
-			defer dbConn.Close() // 确保连接被归还到池中
-			
-			_, err := dbConn.Table("operation_log").Insert(map[string]interface{}{
-				"ip":       c.ClientIP(),
-				"url":      c.Request.URL.Path,
+			defer logWg.Done()
+			user_id := 0
+			if login, ok := cp.Get("login"); ok {
+				if user, u_flag := login.(model.LoginUser); u_flag {
+					user_id = user.ID
+				}
+			}
+			record := map[string]interface{}{
+				"ip":       cp.ClientIP(),
+				"url":      cp.Request.URL.Path,
 				"add_time": t,
 				"user_id":  user_id,
-				"method":   c.Request.Method,
-				"params":   c.Request.URL.Query().Encode(),
+				"method":   cp.Request.Method,
+				"params":   cp.Request.URL.Query().Encode(),
 				"body":     body_str,
-			})
-			middleware.DebugVar("log", err)
+				// 与访问日志、SQL 调试日志中的 request_id 对应
+				"request_id": middleware.RequestIDFromContext(cp),
+			}
+
+			// 获取独立的数据库连接
+			dbConn := public.GetDbConnection()
+			defer dbConn.Close() // 确保连接被归还到池中
+
+			if _, err := dbConn.Table("operation_log").Insert(record); err != nil {
+				// 已有数据库需执行 db_upgrade.sql 增加 request_id 字段
+				logger.Warn("写入操作日志失败", "error", err, "path", record["url"], "request_id", record["request_id"])
+			}
 		}()
 
 		c.Next()
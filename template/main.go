@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	//"github.com/guyigood/gyweb/core/openapi"
 	"{project_name}/controller/sysbase"
 	"{project_name}/public"
 	"{project_name}/service"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"{project_name}/lib"
 	"github.com/guyigood/gyweb/core/utils/datatype"
 	_ "github.com/go-sql-driver/mysql"
@@ -46,18 +50,31 @@ func main() {
 	}()
 
 	// 使用中间件
+	r.Use(middleware.RequestID())
 	r.Use(middleware.Logger())
 	r.Use(middleware.Recovery())
-	r.Use(middleware.CORS())
-	//r.Use(lib.OptionsHandler()) // 处理OPTIONS预检请求
+	r.Use(middleware.Timeout(30 * time.Second)) // 超时后取消通过 c 传入的数据库查询；WebSocket、SSE 请求不受限制
+	// 只允许配置中的前端地址跨域访问，不使用 CORS() 的默认配置（任意来源都可以携带凭据）
+	corsConfig := middleware.DefaultCORSConfig()
+	corsConfig.AllowOrigins = public.SysConfig.Server.AllowOrigins
+	r.Use(middleware.CORSWithConfig(corsConfig))
 	r.Use(middleware.RateLimit(100))
 	CustomAuth(r)      //设置为自定义鉴权
 	r.Use(lib.LogDb()) // 将日志中间件放在认证中间件之后
 	RegRoute(r)
-	// 启动服务器
+	// 输入 routes 时只打印路由表
+	if len(os.Args) > 1 && os.Args[1] == "routes" {
+		r.PrintRoutes(os.Stdout)
+		return
+	}
+	// 服务关闭时等待异步日志写完
+	r.OnShutdown(lib.FlushLogDb)
+	// 启动服务器，收到 SIGINT/SIGTERM 时优雅关闭
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	fmt.Println("正在启动服务器，端口：" + datatype.TypetoStr(public.SysConfig.Server.Port))
 	//fmt.Println("OpenAPI文档地址：http://localhost:8080/swagger")
-	err := r.Run(":" + datatype.TypetoStr(public.SysConfig.Server.Port))
+	err := r.RunWithContext(ctx, ":"+datatype.TypetoStr(public.SysConfig.Server.Port))
 	if err != nil {
 		fmt.Printf("服务器启动失败: %v\n", err)
 	}
@@ -27,6 +27,8 @@ type AppConfig struct {
 		Name  string `json:"name"`
 		Port  int    `json:"port"`
 		Debug bool   `json:"debug"`
+		// AllowOrigins 允许跨域访问的前端地址，如 https://admin.example.com
+		AllowOrigins []string `json:"allow_origins"`
 	} `json:"server"`
 	MQTT struct {
 		Broker   string   `json:"broker"`
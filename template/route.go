@@ -11,24 +11,24 @@ func RegRoute(r *engine.Engine) {
 
 	auth := r.Group("/api/auth")
 	{
-		auth.POST("/login", sysbase.Login)
-		auth.POST("/logout", sysbase.Logout)
-		auth.GET("/userinfo", sysbase.UserInfo)
-		auth.GET("/getmenu", sysbase.GetRoleMenu)
+		auth.POST("/login", sysbase.Login).Name("auth.login")
+		auth.POST("/logout", sysbase.Logout).Name("auth.logout")
+		auth.GET("/userinfo", sysbase.UserInfo).Name("auth.userinfo")
+		auth.GET("/getmenu", sysbase.GetRoleMenu).Name("auth.getmenu")
 
 	}
 	// 数据库通用操作路由
 	db := r.Group("/api/db")
 	{
-		db.GET("/page/:table", dbcommon.Page)
-		db.GET("/list/:table", dbcommon.List)
-		db.GET("/detail/:table", dbcommon.Detail)
-		db.POST("/save/:table", dbcommon.Save)
-		db.GET("/delete/:table", dbcommon.Delete)
-		db.GET("/build", dbcommon.BuildTable)
-		db.POST("/update/:table", dbcommon.UpdateData)
-		db.POST("/batchupdate/:table", dbcommon.BatchUpdate)
-		db.GET("/clearcache", dbcommon.ClearCache)
+		db.GET("/page/:table", dbcommon.Page).Name("db.page")
+		db.GET("/list/:table", dbcommon.List).Name("db.list")
+		db.GET("/detail/:table", dbcommon.Detail).Name("db.detail")
+		db.POST("/save/:table", dbcommon.Save).Name("db.save")
+		db.GET("/delete/:table", dbcommon.Delete).Name("db.delete")
+		db.GET("/build", dbcommon.BuildTable).Name("db.build")
+		db.POST("/update/:table", dbcommon.UpdateData).Name("db.update")
+		db.POST("/batchupdate/:table", dbcommon.BatchUpdate).Name("db.batchupdate")
+		db.GET("/clearcache", dbcommon.ClearCache).Name("db.clearcache")
 
 	}
 